@@ -0,0 +1,134 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+//AttachQueueSize is the max output chunk waiting to send to attached client, the chunk is dropped when queue is full
+var AttachQueueSize = 256
+
+//outputFanout is the writer to copy service output to all attached client
+type outputFanout struct {
+	writers map[*attachWriter]bool
+	locker  sync.RWMutex
+}
+
+func newOutputFanout() (fanout *outputFanout) {
+	fanout = &outputFanout{writers: map[*attachWriter]bool{}}
+	return
+}
+
+//Write will queue data to all attached writer, it is never fail and blocked by slow client
+func (f *outputFanout) Write(p []byte) (n int, err error) {
+	f.locker.RLock()
+	defer f.locker.RUnlock()
+	for writer := range f.writers {
+		writer.queue(p)
+	}
+	n = len(p)
+	return
+}
+
+//attach will add out to receive output, the detach must be called after using
+func (f *outputFanout) attach(out io.Writer) (detach func()) {
+	writer := newAttachWriter(out)
+	f.locker.Lock()
+	f.writers[writer] = true
+	f.locker.Unlock()
+	detach = func() {
+		f.locker.Lock()
+		delete(f.writers, writer)
+		f.locker.Unlock()
+		writer.close()
+	}
+	return
+}
+
+//attachWriter will write output to attached client in background
+type attachWriter struct {
+	out    io.Writer
+	chunks chan []byte
+	done   chan int
+}
+
+func newAttachWriter(out io.Writer) (writer *attachWriter) {
+	writer = &attachWriter{
+		out:    out,
+		chunks: make(chan []byte, AttachQueueSize),
+		done:   make(chan int),
+	}
+	go writer.loop()
+	return
+}
+
+func (a *attachWriter) loop() {
+	for chunk := range a.chunks {
+		a.out.Write(chunk)
+	}
+	close(a.done)
+}
+
+//queue will copy data to queue, it is dropped when queue is full
+func (a *attachWriter) queue(p []byte) {
+	select {
+	case a.chunks <- append([]byte{}, p...):
+	default:
+	}
+}
+
+//close will wait all queued chunk is written
+func (a *attachWriter) close() {
+	close(a.chunks)
+	<-a.done
+}
+
+//Attach will stream the live stdout/stderr of running service matched by group/service pattern to out and forward in to service stdin,
+//the input is dropped when service stdin is not pipe or tty, attached is called with tty before streaming,
+//it return when in is closed or service is exited, the service is not stopped on detaching
+func (m *Manager) Attach(pattern string, in io.Reader, out io.Writer, attached func(tty bool)) (err error) {
+	attaching := []*Running{}
+	m.locker.RLock()
+	for key, running := range m.running {
+		if matchKey(pattern, key) {
+			attaching = append(attaching, running)
+		}
+	}
+	m.locker.RUnlock()
+	if len(attaching) < 1 {
+		err = fmt.Errorf("service %v is not running", pattern)
+		return
+	}
+	if len(attaching) > 1 {
+		err = fmt.Errorf("service %v is matched %v running service, attach require one", pattern, len(attaching))
+		return
+	}
+	running := attaching[0]
+	if attached != nil {
+		attached(running.Service.TTY)
+	}
+	detach := running.output.fanout.attach(out)
+	closed := make(chan int)
+	go func() {
+		buffer := make([]byte, 4096)
+		for {
+			n, readErr := in.Read(buffer)
+			if n > 0 {
+				running.send(buffer[:n])
+			}
+			if readErr != nil {
+				break
+			}
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		detach()
+	case <-running.exited:
+		detach()
+		fmt.Fprintf(out, "\r\n%v is exited\r\n", running.Key)
+	}
+	return
+}
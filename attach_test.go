@@ -0,0 +1,96 @@
+package serviced
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lockedBuffer struct {
+	bytes.Buffer
+	locker sync.Mutex
+}
+
+func (l *lockedBuffer) Write(p []byte) (n int, err error) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	return l.Buffer.Write(p)
+}
+
+func (l *lockedBuffer) String() string {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	return l.Buffer.String()
+}
+
+func TestAttach(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["attach"] = Group{
+		Name:     "attach",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "tty", Path: "/bin/sh", Args: []string{"-c", "sleep 0.2; [ -t 0 ] && echo is tty; read a; echo got $a"}, TTY: true},
+			{Name: "pipe", Path: "/bin/sh", Args: []string{"-c", "sleep 1.5; [ -t 0 ] || echo not tty; read a; echo got $a >&2"}, Stdin: StdinPipe},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "attach")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if m.Attach("attach/*", nil, nil, nil) == nil || m.Attach("attach/none", nil, nil, nil) == nil {
+		t.Error("error")
+		return
+	}
+	//tty is attached first and pipe is attached after tty is exited
+	for _, name := range []string{"tty", "pipe"} {
+		expect := map[string][]string{"tty": {"is tty\r\n", "hello\r\n", "got hello\r\n", "attach/tty is exited"}, "pipe": {"not tty\n", "got hello\n", "attach/pipe is exited"}}[name]
+		reader, writer := io.Pipe()
+		out := &lockedBuffer{}
+		tty := false
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			writer.Write([]byte("hello\n"))
+		}()
+		err = m.Attach("attach/"+name, reader, out, func(t bool) { tty = t })
+		writer.Close()
+		if err != nil || tty != (name == "tty") {
+			t.Errorf("%v,%v", err, tty)
+			return
+		}
+		for _, e := range expect {
+			if !strings.Contains(out.String(), e) {
+				t.Errorf("%v not contains %q", out.String(), e)
+				return
+			}
+		}
+	}
+	//detach
+	m.Groups["attach"].Services[1].Args = []string{"-c", "echo started; sleep 3"}
+	m.Start(ioutil.Discard, "attach/pipe")
+	reader, writer := io.Pipe()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		writer.Close()
+	}()
+	err = m.Attach("attach/pipe", reader, &lockedBuffer{}, nil)
+	if err != nil || len(m.Status("attach/pipe")) != 1 || m.Status("attach/pipe")[0].Pid < 1 {
+		t.Errorf("%v,%v", err, toJSON(m.Status("attach/pipe")))
+		return
+	}
+	//check
+	if (&Service{Name: "a", Path: "a", TTY: true, Stdin: "xx"}).check() == nil {
+		t.Error("error")
+		return
+	}
+}
@@ -0,0 +1,151 @@
+package serviced
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//CgroupRoot is the cgroup v2 unified hierarchy mount point
+var CgroupRoot = "/sys/fs/cgroup"
+
+//CgroupSlice is the cgroup which all service cgroup is created under, it is created under the cgroup of current process
+var CgroupSlice = "serviced.slice"
+
+//cgroupSupported will return true if cgroup v2 is mounted on current host
+func cgroupSupported() bool {
+	_, err := os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+//cgroupSelf will return the cgroup v2 path of current process
+func cgroupSelf() (path string, err error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			path = filepath.Join(CgroupRoot, strings.TrimPrefix(line, "0::"))
+			return
+		}
+	}
+	err = fmt.Errorf("cgroup v2 of current process is not found")
+	return
+}
+
+//createCgroup will create cgroup for service by key under the cgroup of current process and apply limits
+func createCgroup(key string, limits *Limits) (path string, err error) {
+	base, err := cgroupSelf()
+	if err != nil {
+		return
+	}
+	slice := filepath.Join(base, CgroupSlice)
+	err = os.MkdirAll(slice, 0755)
+	if err != nil {
+		return
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(slice, "cgroup.controllers"))
+	available := map[string]bool{}
+	for _, controller := range strings.Fields(string(data)) {
+		available[controller] = true
+	}
+	for _, controller := range []string{"memory", "cpu", "pids"} {
+		if !available[controller] {
+			if base == filepath.Clean(CgroupRoot) {
+				err = fmt.Errorf("cgroup controller %v is not enabled on %v, it will not be enabled on cgroup root", controller, base)
+				return
+			}
+			err = writeCgroup(base, "cgroup.subtree_control", "+"+controller)
+			if err != nil {
+				return
+			}
+		}
+		err = writeCgroup(slice, "cgroup.subtree_control", "+"+controller)
+		if err != nil {
+			return
+		}
+	}
+	path = filepath.Join(slice, strings.ReplaceAll(key, "/", "-"))
+	if _, e := os.Stat(path); e == nil {
+		killCgroup(path)
+		removeCgroup(path)
+	}
+	err = os.Mkdir(path, 0755)
+	if err != nil {
+		return
+	}
+	if len(limits.Memory) > 0 {
+		memory, _ := parseSize(limits.Memory)
+		val := "max"
+		if memory >= 0 {
+			val = fmt.Sprintf("%v", memory)
+		}
+		err = writeCgroup(path, "memory.max", val)
+	}
+	if err == nil && limits.CPU > 0 {
+		err = writeCgroup(path, "cpu.max", fmt.Sprintf("%v 100000", int64(limits.CPU*100000)))
+	}
+	if err == nil && limits.Pids > 0 {
+		err = writeCgroup(path, "pids.max", fmt.Sprintf("%v", limits.Pids))
+	}
+	if err != nil {
+		removeCgroup(path)
+	}
+	return
+}
+
+func writeCgroup(path, name, val string) (err error) {
+	err = ioutil.WriteFile(filepath.Join(path, name), []byte(val), 0644)
+	if err != nil {
+		err = fmt.Errorf("write %v to %v/%v fail with %v", val, path, name, err)
+	}
+	return
+}
+
+//killCgroup will kill all process in cgroup
+func killCgroup(path string) (err error) {
+	err = ioutil.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0644)
+	if err == nil {
+		return
+	}
+	//cgroup.kill is not supported before linux 5.14
+	data, err := ioutil.ReadFile(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Fields(string(data)) {
+		pid, e := strconv.Atoi(line)
+		if e == nil {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	return
+}
+
+//removeCgroup will wait all process exited and remove cgroup
+func removeCgroup(path string) (err error) {
+	for i := 0; i < 50; i++ {
+		err = os.Remove(path)
+		if err == nil || os.IsNotExist(err) {
+			err = nil
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return
+}
+
+//cgroupMemory will return the memory.current of cgroup
+func cgroupMemory(path string) (memory int64, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(path, "memory.current"))
+	if err == nil {
+		memory, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	return
+}
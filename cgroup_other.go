@@ -0,0 +1,32 @@
+//go:build !linux
+// +build !linux
+
+package serviced
+
+import (
+	"fmt"
+	"runtime"
+)
+
+//cgroupSupported will always return false because cgroup is only supported on linux
+func cgroupSupported() bool {
+	return false
+}
+
+func createCgroup(key string, limits *Limits) (path string, err error) {
+	err = fmt.Errorf("cgroup is not supported on %v", runtime.GOOS)
+	return
+}
+
+func killCgroup(path string) (err error) {
+	return
+}
+
+func removeCgroup(path string) (err error) {
+	return
+}
+
+func cgroupMemory(path string) (memory int64, err error) {
+	err = fmt.Errorf("cgroup is not supported on %v", runtime.GOOS)
+	return
+}
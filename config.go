@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -26,37 +28,221 @@ func marshal(filename string, v interface{}) (err error) {
 	return
 }
 
+const (
+	//ServiceSimple is the long-running service type
+	ServiceSimple = "simple"
+	//ServiceOneshot is the service type which is success when exit with 0
+	ServiceOneshot = "oneshot"
+)
+
 //Service is struct to record service configure
 type Service struct {
-	Name   string   `json:"name"`
-	Path   string   `json:"path"`
-	Args   []string `json:"args"`
-	Env    []string `json:"env"`
-	Stdout string   `json:"stdout"`
-	Stderr string   `json:"stderr"`
-	Dir    string   `json:"dir"`
+	Name      string         `json:"name"`
+	Path      string         `json:"path"`
+	Args      []string       `json:"args"`
+	Env       []string       `json:"env"`
+	Stdout    string         `json:"stdout"`
+	Stderr    string         `json:"stderr"`
+	Dir       string         `json:"dir"`
+	User      string         `json:"user"`
+	Group     string         `json:"group"`
+	Groups    []string       `json:"groups"`
+	Limits    *Limits        `json:"limits"`
+	Sandbox   *Sandbox       `json:"sandbox"`
+	Schedule  string         `json:"schedule"`
+	Overlap   string         `json:"overlap"`
+	Type      string         `json:"type"`
+	Instances int            `json:"instances"`
+	Ready     *Probe         `json:"ready"`
+	Restart   *RestartPolicy `json:"restart"`
+	LogDriver *LogDriver     `json:"log_driver"`
+	//LogTimestamps/LogPrefix will prepend timestamp and group/service[stream] on each line of output file
+	LogTimestamps      bool   `json:"log_timestamps"`
+	LogTimestampFormat string `json:"log_timestamp_format"`
+	LogPrefix          bool   `json:"log_prefix"`
+	//CombinedOutput will redirect stderr to stdout like 2>&1, both is written to stdout file in the order it is written
+	CombinedOutput bool `json:"combined_output"`
+	//Stdin is the service stdin, pipe is keeping stdin writable by send command, file:<path> is reading from file relative to dir,
+	//others is used as literal string
+	Stdin string `json:"stdin"`
+	//Sockets is the address like tcp://127.0.0.1:8080 or unix:///run/web.sock bound by manager and passed to service from fd 3
+	//with LISTEN_FDS/LISTEN_PID env, it is kept open across service restarts
+	Sockets []string `json:"sockets"`
+	//Ports is the port allocated by manager and exposed as ${PORT_<NAME>} in path/args/env/dir, it is kept across restarts
+	Ports []*Port `json:"ports"`
+	//Watch is the file glob relative to configure directory, the service is restarted when matched file is changed in dev mode,
+	//the matched directory is watched recursively, WatchIgnore is the glob to ignore by relative path or base name
+	Watch         []string `json:"watch"`
+	WatchIgnore   []string `json:"watch_ignore"`
+	WatchDebounce string   `json:"watch_debounce"`
+	//ReloadSignal is the signal sent by reload-service, default is SIGHUP
+	ReloadSignal string `json:"reload_signal"`
+	//TTY is allocating pty as stdin/stdout/stderr of service, the stdin is writable by send/attach command
+	TTY bool `json:"tty"`
+	//LogFileMode/LogDirMode is the octal mode of created log file and directory, default is 0640/0750
+	LogFileMode string `json:"log_file_mode"`
+	LogDirMode  string `json:"log_dir_mode"`
+	Hooks
+}
+
+//check will check service configure
+func (s *Service) check() (err error) {
+	if len(s.Name) < 1 || len(s.Path) < 1 {
+		err = fmt.Errorf("service name/path is required")
+		return
+	}
+	if strings.ContainsAny(s.Name, "/@") {
+		err = fmt.Errorf("service name %v must not contains / or @", s.Name)
+		return
+	}
+	cred, err := lookupCredential(s)
+	if err == nil && cred != nil {
+		err = cred.check()
+	}
+	if err == nil && s.Limits != nil {
+		err = s.Limits.check()
+	}
+	if err == nil && s.Sandbox != nil {
+		err = s.Sandbox.check()
+	}
+	if err == nil && len(s.Schedule) > 0 {
+		_, err = parseSchedule(s.Schedule)
+	}
+	if err == nil && len(s.Overlap) > 0 && s.Overlap != OverlapSkip && s.Overlap != OverlapQueue {
+		err = fmt.Errorf("overlap must be %v or %v", OverlapSkip, OverlapQueue)
+	}
+	if err == nil && len(s.Type) > 0 && s.Type != ServiceSimple && s.Type != ServiceOneshot {
+		err = fmt.Errorf("type must be %v or %v", ServiceSimple, ServiceOneshot)
+	}
+	if err == nil && s.Instances < 0 {
+		err = fmt.Errorf("instances must be positive")
+	}
+	if err == nil && s.Instances > 0 && len(s.Schedule) > 0 {
+		err = fmt.Errorf("instances is not supported on schedule service")
+	}
+	if err == nil && s.Ready != nil {
+		err = s.Ready.check()
+	}
+	if err == nil && s.Restart != nil {
+		err = s.Restart.check()
+	}
+	if err == nil && s.CombinedOutput && len(s.Stdout) < 1 {
+		err = fmt.Errorf("stdout is required on combined_output")
+	}
+	if err == nil && s.CombinedOutput && len(s.Stderr) > 0 && s.Stderr != s.Stdout {
+		err = fmt.Errorf("stderr must be empty or same as stdout on combined_output")
+	}
+	if err == nil && s.LogDriver != nil {
+		err = s.LogDriver.check()
+	}
+	if err == nil && s.TTY {
+		err = checkTTYSupported()
+	}
+	if err == nil {
+		_, err = s.reloadSignal()
+	}
+	for _, addr := range s.Sockets {
+		if err == nil {
+			_, _, err = parseSocket(addr)
+		}
+	}
+	if err == nil {
+		err = checkPorts(s.Ports)
+	}
+	if err == nil {
+		err = s.checkWatch()
+	}
+	if err == nil && len(s.Sockets) > 0 {
+		err = checkShimSupported("sockets")
+	}
+	if err == nil && s.TTY && len(s.Stdin) > 0 && s.Stdin != StdinPipe {
+		err = fmt.Errorf("stdin must be empty or pipe on tty")
+	}
+	if err == nil {
+		_, _, err = s.logModes()
+	}
+	if err == nil && s.Restart != nil && len(s.Schedule) > 0 {
+		err = fmt.Errorf("restart is not supported on schedule service")
+	}
+	return
+}
+
+//logModes will return the mode of created log file and directory
+func (s *Service) logModes() (fileMode, dirMode os.FileMode, err error) {
+	fileMode, dirMode = DefaultLogFileMode, DefaultLogDirMode
+	for _, m := range []struct {
+		name  string
+		value string
+		out   *os.FileMode
+	}{{"log_file_mode", s.LogFileMode, &fileMode}, {"log_dir_mode", s.LogDirMode, &dirMode}} {
+		if len(m.value) < 1 {
+			continue
+		}
+		val, e := strconv.ParseUint(m.value, 8, 32)
+		if e != nil || val > 0777 {
+			err = fmt.Errorf("%v %v is invalid", m.name, m.value)
+			return
+		}
+		*m.out = os.FileMode(val)
+	}
+	return
 }
 
 //Group is struct to record the service group configure
 type Group struct {
 	Name     string    `json:"name"`
 	Services []Service `json:"services"`
+	Proxy    *Proxy    `json:"proxy,omitempty"`
 	Filename string    `json:"-"`
 	Enable   int       `json:"-"`
+	Hooks
+}
+
+//check will check group configure and all service configure
+func (g *Group) check() (err error) {
+	if len(g.Name) < 1 {
+		err = fmt.Errorf("group name is empty")
+		return
+	}
+	if len(g.Services) < 1 {
+		err = fmt.Errorf("group %v services is empty", g.Name)
+		return
+	}
+	for index, service := range g.Services {
+		err = service.check()
+		if err != nil {
+			err = fmt.Errorf("group %v %v service %v", g.Name, index, err)
+			return
+		}
+	}
+	if g.Proxy != nil {
+		err = g.Proxy.check(g)
+		if err != nil {
+			err = fmt.Errorf("group %v %v", g.Name, err)
+		}
+	}
+	return
 }
 
 //Config is current running configure
 type Config struct {
-	Filename string           `json:"-"`
-	Includes map[string]int   `json:"includes"`
-	Groups   map[string]Group `json:"-"`
+	Filename string `json:"-"`
+	//Console is the console listen address, the client is reading it by --config
+	Console   string           `json:"console,omitempty"`
+	Includes  map[string]int   `json:"includes"`
+	Notify    *Notify          `json:"notify,omitempty"`
+	LogDriver *LogDriver       `json:"log_driver,omitempty"`
+	Groups    map[string]Group `json:"-"`
 }
 
 func (c *Config) copy() (config *Config) {
 	config = &Config{
-		Filename: c.Filename,
-		Includes: map[string]int{},
-		Groups:   map[string]Group{},
+		Filename:  c.Filename,
+		Console:   c.Console,
+		Includes:  map[string]int{},
+		Notify:    c.Notify,
+		LogDriver: c.LogDriver,
+		Groups:    map[string]Group{},
 	}
 	for k, v := range c.Includes {
 		config.Includes[k] = v
@@ -86,18 +272,26 @@ func (c *Config) Load() (err error) {
 	} else if err != nil {
 		return
 	}
+	if c.Notify != nil {
+		err = c.Notify.check()
+		if err != nil {
+			return
+		}
+	}
+	if c.LogDriver != nil {
+		err = c.LogDriver.check()
+		if err != nil {
+			return
+		}
+	}
 	for file, enable := range c.Includes {
 		group := &Group{}
 		err = unmarshal(file, group)
 		if err != nil {
 			return
 		}
-		if len(group.Name) < 1 {
-			log.Warnf("load group from %v fail with group name is empty", file)
-			continue
-		}
-		if len(group.Services) < 1 {
-			log.Warnf("load group from %v fail with service list is empty", file)
+		if e := group.check(); e != nil {
+			log.Warnf("load group from %v fail with %v", file, e)
 			continue
 		}
 		group.Filename = file
@@ -139,6 +333,9 @@ func (c *Config) Reload(name string) (err error) {
 	}
 	newGroup := &Group{}
 	err = unmarshal(group.Filename, newGroup)
+	if err == nil {
+		err = newGroup.check()
+	}
 	if err == nil {
 		newGroup.Filename = group.Filename
 		newGroup.Enable = group.Enable
@@ -176,16 +373,11 @@ func (c *Config) Add(filename string, enable int) (group Group, err error) {
 		err = fmt.Errorf("group %v is exists from %v", group.Name, old.Filename)
 		return
 	}
-	if len(group.Services) < 1 {
-		err = fmt.Errorf("group %v services is empty from %v", group.Name, group.Filename)
+	err = group.check()
+	if err != nil {
+		err = fmt.Errorf("%v from %v", err, group.Filename)
 		return
 	}
-	for index, service := range group.Services {
-		if len(service.Name) < 1 || len(service.Path) < 1 {
-			err = fmt.Errorf("group %v %v service name/path is required", group.Name, index)
-			return
-		}
-	}
 	copy := c.copy()
 	copy.Includes[filename] = enable
 	err = copy.Save()
@@ -8,13 +8,17 @@ import (
 	"net"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 //Console is service manager cli
 type Console struct {
 	conn    net.Conn
 	TempDir string
-	Waiter  chan error
+	//Filename is the daemon configure file, the console address is read from it when it is configured
+	Filename string
+	Timeout  time.Duration
+	Waiter   chan error
 }
 
 //NewConsole will return new console
@@ -25,8 +29,20 @@ func NewConsole() (console *Console) {
 	return
 }
 
-//Bootstrap will dial to console by console.serviced.txt file
+//Bootstrap will dial to console by console address in Filename, it will fallback to console.serviced.txt file when not configured
 func (c *Console) Bootstrap() (err error) {
+	if len(c.Filename) > 0 {
+		config := &Config{}
+		err = unmarshal(c.Filename, config)
+		if err != nil {
+			err = fmt.Errorf("read console address from %v fail with %v", c.Filename, err)
+			return
+		}
+		if len(config.Console) > 0 {
+			err = c.Dial(config.Console)
+			return
+		}
+	}
 	addrFile := filepath.Join(c.TempDir, "console.serviced.txt")
 	addrBytes, err := ioutil.ReadFile(addrFile)
 	if err != nil {
@@ -39,7 +55,11 @@ func (c *Console) Bootstrap() (err error) {
 
 //Dial will connect the console
 func (c *Console) Dial(remote string) (err error) {
-	c.conn, err = net.Dial("tcp", remote)
+	if c.Timeout > 0 {
+		c.conn, err = net.DialTimeout("tcp", remote, c.Timeout)
+	} else {
+		c.conn, err = net.Dial("tcp", remote)
+	}
 	if err != nil {
 		err = fmt.Errorf("connect console by %v fail with %v", remote, err)
 		return
@@ -55,45 +75,149 @@ func (c *Console) Close() (err error) {
 
 //Add will add group service to manager
 func (c *Console) Add(groupFile string) (err error) {
-	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"add", groupFile}))
-	if err == nil {
-		err = <-c.Waiter
-	}
+	err = c.call("add", groupFile)
 	return
 }
 
 //Remove will add group service to manager
 func (c *Console) Remove(group string) (err error) {
-	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"remove", group}))
-	if err == nil {
-		err = <-c.Waiter
-	}
+	err = c.call("remove", group)
 	return
 }
 
-//Start will start all service in group
-func (c *Console) Start(group string) (err error) {
-	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"start", group}))
-	if err == nil {
-		err = <-c.Waiter
-	}
+//Start will start all service in group, target is all, group name or group/service with glob like web/* or */worker-*
+func (c *Console) Start(target string) (err error) {
+	err = c.call("start", target)
 	return
 }
 
-//Stop will stop all service in group
-func (c *Console) Stop(group string) (err error) {
-	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"stop", group}))
-	if err == nil {
-		err = <-c.Waiter
+//Stop will stop all service in group, target is all, group name or group/service with glob like web/* or */worker-*
+func (c *Console) Stop(target string) (err error) {
+	err = c.call("stop", target)
+	return
+}
+
+//Restart will stop and start service, target is all, group name or group/service with glob like web/* or */worker-*
+func (c *Console) Restart(target string) (err error) {
+	err = c.call("restart", target)
+	return
+}
+
+//Scale will change the instance count of service by group/service
+func (c *Console) Scale(service string, count int) (err error) {
+	err = c.call("scale", service, fmt.Sprintf("%v", count))
+	return
+}
+
+//RollingRestart will restart service one by one and wait each to be ready, target is group name or group/service with glob
+func (c *Console) RollingRestart(target string) (err error) {
+	err = c.call("restart", target, "--rolling")
+	return
+}
+
+//Wait will block until service reach the state, state is running/ready/stopped/exited, timeout 0 is waiting forever
+func (c *Console) Wait(target, state string, timeout time.Duration) (err error) {
+	err = c.call("wait", target, state, timeout.String())
+	return
+}
+
+//List will list all service info in group, target is all, group name or group/service with glob like web/* or */worker-*
+func (c *Console) List(target string) (err error) {
+	err = c.call("list", target)
+	return
+}
+
+//Status will show service status by format, target is all, group name or group/service with glob,
+//format is table/wide/json/yaml, tmpl is go template executed on each status if it is not empty
+func (c *Console) Status(target, format, tmpl string) (err error) {
+	err = c.call("list", target, format, tmpl)
+	return
+}
+
+//History will show service exit history by format, target is all, group name or group/service with glob,
+//format is table/wide/json/yaml, tmpl is go template executed on each history if it is not empty
+func (c *Console) History(target, format, tmpl string) (err error) {
+	err = c.call("history", target, format, tmpl)
+	return
+}
+
+//Send will write data to stdin of service by group/service, the stdin of service must be pipe
+func (c *Console) Send(service string, data string) (err error) {
+	err = c.call("send", service, data)
+	return
+}
+
+//Signal will send signal like SIGHUP, HUP or 1 to running service, target is all, group name or group/service with glob
+func (c *Console) Signal(target, signal string) (err error) {
+	err = c.call("signal", target, signal)
+	return
+}
+
+//ReloadService will send reload_signal of service to running service, target is all, group name or group/service with glob
+func (c *Console) ReloadService(target string) (err error) {
+	err = c.call("reload", target)
+	return
+}
+
+//Attach will stream the live output of service to out and forward in to service stdin, attached is called with tty
+//before streaming, it return when in is closed or service is exited
+func (c *Console) Attach(service string, in io.Reader, out io.Writer, attached func(tty bool)) (err error) {
+	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"attach", service}))
+	if err != nil {
+		return
 	}
+	reader := bufio.NewReader(c.conn)
+	tty := false
+	for {
+		var line string
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			err = fmt.Errorf("console is closed by %v", err)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "==ERR:") {
+			err = fmt.Errorf("%v", strings.TrimPrefix(line, "==ERR:"))
+			return
+		}
+		if strings.HasPrefix(line, "==OK:") {
+			tty = strings.TrimPrefix(line, "==OK:") == "tty"
+			break
+		}
+		fmt.Fprintf(out, "%v\n", line)
+	}
+	if attached != nil {
+		attached(tty)
+	}
+	done := make(chan int, 2)
+	go func() {
+		io.Copy(out, reader)
+		done <- 1
+	}()
+	go func() {
+		io.Copy(c.conn, in)
+		done <- 1
+	}()
+	<-done
 	return
 }
 
-//List will list all service info in group
-func (c *Console) List(group string) (err error) {
-	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON([]string{"list", group}))
-	if err == nil {
+//call will send command to console and wait the result, it fail when timeout is reached
+func (c *Console) call(args ...string) (err error) {
+	_, err = fmt.Fprintf(c.conn, "%v\n", toJSON(args))
+	if err != nil {
+		return
+	}
+	if c.Timeout < 1 {
 		err = <-c.Waiter
+		return
+	}
+	timer := time.NewTimer(c.Timeout)
+	defer timer.Stop()
+	select {
+	case err = <-c.Waiter:
+	case <-timer.C:
+		err = fmt.Errorf("wait command %v result timeout", args[0])
 	}
 	return
 }
@@ -108,7 +232,7 @@ func (c *Console) CopyTo(out io.Writer) (err error) {
 			break
 		}
 		info := string(buffer)
-		info = strings.TrimSpace(info)
+		info = strings.TrimRight(info, "\r\n")
 		if strings.HasPrefix(info, "==ERR:") {
 			c.Waiter <- fmt.Errorf("%v", strings.TrimPrefix(info, "==ERR:"))
 		} else if strings.HasPrefix(info, "==OK:") {
@@ -117,5 +241,9 @@ func (c *Console) CopyTo(out io.Writer) (err error) {
 			fmt.Fprintf(out, "%v\n", info)
 		}
 	}
+	select {
+	case c.Waiter <- fmt.Errorf("console is closed by %v", err):
+	default:
+	}
 	return
 }
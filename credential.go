@@ -0,0 +1,122 @@
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//credential is the resolved user/group which service is running as,
+//the supplementary groups is not changed when NoSetGroups is true because it require root privileges
+type credential struct {
+	UID         uint32
+	GID         uint32
+	Groups      []uint32
+	NoSetGroups bool
+}
+
+//lookupCredential will resolve service user/group/groups to credential, it will return nil when nothing is configured
+func lookupCredential(service *Service) (cred *credential, err error) {
+	if len(service.User) < 1 && len(service.Group) < 1 && len(service.Groups) < 1 {
+		return
+	}
+	root := os.Geteuid() == 0
+	cred = &credential{
+		UID:         uint32(os.Geteuid()),
+		GID:         uint32(os.Getegid()),
+		NoSetGroups: !root,
+	}
+	if len(service.User) > 0 {
+		var groups []uint32
+		cred.UID, cred.GID, groups, err = lookupUser(service.User)
+		if err != nil {
+			return
+		}
+		if root {
+			//the supplementary groups of user is loaded like login
+			cred.Groups = groups
+		}
+	}
+	if len(service.Group) > 0 {
+		cred.GID, err = lookupGroup(service.Group)
+		if err != nil {
+			return
+		}
+	}
+	for _, name := range service.Groups {
+		var gid uint32
+		gid, err = lookupGroup(name)
+		if err != nil {
+			return
+		}
+		if !containsID(cred.Groups, gid) {
+			cred.Groups = append(cred.Groups, gid)
+		}
+	}
+	return
+}
+
+//lookupUser will return uid, primary gid and supplementary gids by user name or uid
+func lookupUser(name string) (uid, gid uint32, groups []uint32, err error) {
+	u, err := user.Lookup(name)
+	if _, ok := err.(user.UnknownUserError); ok {
+		u, err = user.LookupId(name)
+	}
+	if err != nil {
+		err = fmt.Errorf("lookup user %v fail with %v", name, err)
+		return
+	}
+	uid, err = parseID(u.Uid)
+	if err == nil {
+		gid, err = parseID(u.Gid)
+	}
+	if err != nil {
+		err = fmt.Errorf("parse user %v fail with %v", name, err)
+		return
+	}
+	ids, groupErr := u.GroupIds()
+	if groupErr != nil {
+		log.Warnf("lookup groups of user %v fail with %v, only primary group is used", name, groupErr)
+	}
+	for _, id := range ids {
+		if gid, parseErr := parseID(id); parseErr == nil && !containsID(groups, gid) {
+			groups = append(groups, gid)
+		}
+	}
+	return
+}
+
+//lookupGroup will return gid by group name or gid
+func lookupGroup(name string) (gid uint32, err error) {
+	g, err := user.LookupGroup(name)
+	if _, ok := err.(user.UnknownGroupError); ok {
+		g, err = user.LookupGroupId(name)
+	}
+	if err != nil {
+		err = fmt.Errorf("lookup group %v fail with %v", name, err)
+		return
+	}
+	gid, err = parseID(g.Gid)
+	if err != nil {
+		err = fmt.Errorf("parse group %v fail with %v", name, err)
+	}
+	return
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func parseID(id string) (val uint32, err error) {
+	v, err := strconv.ParseUint(id, 10, 32)
+	val = uint32(v)
+	return
+}
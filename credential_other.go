@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+//check will check if current process having privileges to switch to credential
+func (c *credential) check() (err error) {
+	if os.Geteuid() == 0 {
+		return
+	}
+	if int(c.UID) != os.Geteuid() || int(c.GID) != os.Getegid() || len(c.Groups) > 0 {
+		err = fmt.Errorf("switch to uid %v gid %v groups %v require root privileges, current uid is %v", c.UID, c.GID, c.Groups, os.Geteuid())
+	}
+	return
+}
+
+//apply will set the credential to command
+func (c *credential) apply(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:         c.UID,
+		Gid:         c.GID,
+		Groups:      c.Groups,
+		NoSetGroups: c.NoSetGroups,
+	}
+}
+
+//chown will change the owner of path to credential, it is skipped when not running as root
+func (c *credential) chown(path string) (err error) {
+	if os.Geteuid() != 0 {
+		return
+	}
+	err = os.Lchown(path, int(c.UID), int(c.GID))
+	return
+}
@@ -0,0 +1,83 @@
+package serviced
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCredential(t *testing.T) {
+	cred, err := lookupCredential(&Service{})
+	if err != nil || cred != nil {
+		t.Error("error")
+		return
+	}
+	cred, err = lookupCredential(&Service{User: "root", Groups: []string{"0"}})
+	if err != nil || cred.UID != 0 || cred.GID != 0 || len(cred.Groups) != 1 {
+		t.Errorf("%v,%v", err, cred)
+		return
+	}
+	if os.Geteuid() == 0 && cred.check() != nil {
+		t.Error("error")
+		return
+	}
+	if os.Geteuid() != 0 && cred.check() == nil {
+		t.Error("error")
+		return
+	}
+	_, err = lookupCredential(&Service{User: "not-exists-user-xx"})
+	if err == nil {
+		t.Error("error")
+		return
+	}
+	_, err = lookupCredential(&Service{Group: "not-exists-group-xx"})
+	if err == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestCredentialSelf(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	current, err := user.Current()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	cred, err := lookupCredential(&Service{User: current.Username})
+	if err != nil || cred.NoSetGroups != (os.Geteuid() != 0) || cred.check() != nil {
+		t.Errorf("%v,%v", err, cred)
+		return
+	}
+	if os.Geteuid() != 0 && len(cred.Groups) > 0 {
+		t.Errorf("%v", cred)
+		return
+	}
+	//the non-root daemon can run service as itself without changing groups
+	defer os.Remove("test-credential.log")
+	m := NewManager()
+	m.init()
+	m.Groups["credential"] = Group{
+		Name:     "credential",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "self", Path: "/bin/sh", Args: []string{"-c", "id -u"}, User: current.Username, Stdout: "test-credential.log", Type: ServiceOneshot},
+		},
+	}
+	err = m.Start(ioutil.Discard, "credential/self")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, _ := ioutil.ReadFile("test-credential.log")
+	if strings.TrimSpace(string(data)) != fmt.Sprintf("%v", os.Geteuid()) {
+		t.Errorf("%v", string(data))
+		return
+	}
+}
@@ -0,0 +1,21 @@
+package serviced
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+//check will always return error because switching user is not supported on windows
+func (c *credential) check() (err error) {
+	err = fmt.Errorf("user/group is not supported on windows")
+	return
+}
+
+//apply will do nothing on windows
+func (c *credential) apply(cmd *exec.Cmd) {
+}
+
+//chown will do nothing on windows
+func (c *credential) chown(path string) (err error) {
+	return
+}
@@ -0,0 +1,203 @@
+package serviced
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//cronSchedule is the schedule to return next run time after given time
+type cronSchedule interface {
+	Next(t time.Time) time.Time
+}
+
+//everySchedule is the schedule by fixed interval like @every 5m
+type everySchedule struct {
+	Every time.Duration
+}
+
+//Next will return next run time after t
+func (e *everySchedule) Next(t time.Time) time.Time {
+	return t.Add(e.Every)
+}
+
+//cronSpec is the schedule by cron expression with minute, hour, day of month, month, day of week
+type cronSpec struct {
+	Minute uint64
+	Hour   uint64
+	Dom    uint64
+	Month  uint64
+	Dow    uint64
+	//DomStar/DowStar is true when day of month/week is *, when both is restricted, any is matched will run
+	DomStar bool
+	DowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDowNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+//parseSchedule will parse cron expression, descriptor like @daily or @every <duration> to schedule
+func parseSchedule(spec string) (schedule cronSchedule, err error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		var every time.Duration
+		every, err = time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err == nil && every < time.Second {
+			err = fmt.Errorf("duration must be at least 1s")
+		}
+		if err != nil {
+			err = fmt.Errorf("parse schedule %v fail with %v", spec, err)
+			return
+		}
+		schedule = &everySchedule{Every: every}
+		return
+	}
+	if descriptor, ok := cronDescriptors[spec]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		err = fmt.Errorf("parse schedule %v fail with expected 5 fields, but %v", spec, len(fields))
+		return
+	}
+	cron := &cronSpec{
+		DomStar: strings.HasPrefix(fields[2], "*"),
+		DowStar: strings.HasPrefix(fields[4], "*"),
+	}
+	cron.Minute, err = parseCronField(fields[0], 0, 59, nil)
+	if err == nil {
+		cron.Hour, err = parseCronField(fields[1], 0, 23, nil)
+	}
+	if err == nil {
+		cron.Dom, err = parseCronField(fields[2], 1, 31, nil)
+	}
+	if err == nil {
+		cron.Month, err = parseCronField(fields[3], 1, 12, cronMonthNames)
+	}
+	if err == nil {
+		cron.Dow, err = parseCronField(fields[4], 0, 7, cronDowNames)
+	}
+	if err != nil {
+		err = fmt.Errorf("parse schedule %v fail with %v", spec, err)
+		return
+	}
+	if cron.Dow&(1<<7) > 0 {
+		//7 is also sunday
+		cron.Dow |= 1
+	}
+	schedule = cron
+	return
+}
+
+//parseCronField will parse one cron field like *, */5, 1,2,3, 1-5, 1-10/2 to bits
+func parseCronField(field string, min, max int, names map[string]int) (bits uint64, err error) {
+	parseValue := func(val string) (int, error) {
+		if n, ok := names[strings.ToLower(val)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(val)
+		if err == nil && (n < min || n > max) {
+			err = fmt.Errorf("%v is out of range [%v,%v]", n, min, max)
+		}
+		return n, err
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step < 1 {
+				err = fmt.Errorf("step %v is invalid", part[idx+1:])
+				return
+			}
+			part = part[:idx]
+		}
+		var start, end int
+		switch {
+		case part == "*":
+			start, end = min, max
+		case strings.Contains(part, "-"):
+			ranges := strings.SplitN(part, "-", 2)
+			start, err = parseValue(ranges[0])
+			if err == nil {
+				end, err = parseValue(ranges[1])
+			}
+			if err == nil && start > end {
+				err = fmt.Errorf("range %v is invalid", part)
+			}
+		default:
+			start, err = parseValue(part)
+			end = start
+			if step > 1 {
+				end = max
+			}
+		}
+		if err != nil {
+			return
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return
+}
+
+//Next will return next run time after t, it will return zero time if not found in 5 years
+func (c *cronSpec) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+WRAP:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+	for c.Month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !c.matchDay(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for c.Hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for c.Minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
+
+func (c *cronSpec) matchDay(t time.Time) bool {
+	dom := c.Dom&(1<<uint(t.Day())) > 0
+	dow := c.Dow&(1<<uint(t.Weekday())) > 0
+	if c.DomStar || c.DowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
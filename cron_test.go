@@ -0,0 +1,132 @@
+package serviced
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	base := time.Date(2020, 1, 31, 10, 30, 15, 0, time.UTC)
+	for spec, next := range map[string]time.Time{
+		"* * * * *":         time.Date(2020, 1, 31, 10, 31, 0, 0, time.UTC),
+		"*/15 * * * *":      time.Date(2020, 1, 31, 10, 45, 0, 0, time.UTC),
+		"0 * * * *":         time.Date(2020, 1, 31, 11, 0, 0, 0, time.UTC),
+		"5 8 * * *":         time.Date(2020, 2, 1, 8, 5, 0, 0, time.UTC),
+		"0 0 29 2 *":        time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 * * sun":       time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":         time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * mon":      time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC),
+		"0 9-17/4 * jan *":  time.Date(2020, 1, 31, 13, 0, 0, 0, time.UTC),
+		"0 0 1 mar-apr *":   time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		"@daily":            time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		"@yearly":           time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"@every 5m":         time.Date(2020, 1, 31, 10, 35, 15, 0, time.UTC),
+		"30,45 10,12 * * *": time.Date(2020, 1, 31, 10, 45, 0, 0, time.UTC),
+	} {
+		schedule, err := parseSchedule(spec)
+		if err != nil {
+			t.Errorf("%v,%v", spec, err)
+			return
+		}
+		if val := schedule.Next(base); !val.Equal(next) {
+			t.Errorf("%v,%v,%v", spec, val, next)
+			return
+		}
+	}
+	if schedule, _ := parseSchedule("0 0 30 2 *"); !schedule.Next(base).IsZero() {
+		t.Error("error")
+		return
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "x * * * *", "@every 1ms", "@every x"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("%v", spec)
+			return
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	group := &Group{
+		Name:     "schedule",
+		Filename: "test-service.json",
+	}
+	service := &Service{
+		Name:     "exit",
+		Path:     "/bin/sh",
+		Args:     []string{"-c", "exit 3"},
+		Schedule: "@every 1s",
+	}
+	m := NewManager()
+	schedule, err := m.StartSchedule(group, service)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = m.StartSchedule(group, service); err == nil {
+		t.Error("error")
+		return
+	}
+	time.Sleep(1500 * time.Millisecond)
+	err = m.StopSchedule(group.Name, service.Name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if schedule.Runs != 1 || schedule.LastCode != 3 || schedule.LastRun.IsZero() {
+		t.Errorf("%v,%v", schedule.Runs, schedule.LastCode)
+		return
+	}
+	if err = m.StopSchedule(group.Name, service.Name); err == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestScheduleStopOnFire(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	group := &Group{
+		Name:     "schedule",
+		Filename: "test-service.json",
+	}
+	service := &Service{
+		Name:     "sleep",
+		Path:     "/bin/sleep",
+		Args:     []string{"10"},
+		Schedule: "@every 1s",
+	}
+	m := NewManager()
+	schedule, err := m.StartSchedule(group, service)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	//stop when the job is marked running and maybe not started
+	for {
+		m.locker.RLock()
+		running := schedule.running
+		m.locker.RUnlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	begin := time.Now()
+	err = m.StopSchedule(group.Name, service.Name)
+	if err != nil || time.Since(begin) > 5*time.Second {
+		t.Errorf("%v,%v", err, time.Since(begin))
+		return
+	}
+	m.locker.RLock()
+	running, scheduled := len(m.running), schedule.running
+	m.locker.RUnlock()
+	if running != 0 || scheduled {
+		t.Errorf("%v,%v", running, scheduled)
+		return
+	}
+}
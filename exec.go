@@ -0,0 +1,38 @@
+package serviced
+
+import (
+	"os/exec"
+)
+
+//execSpec is the setup which must be applied in child process before exec service
+type execSpec struct {
+	Path       string            `json:"path"`
+	Cgroup     string            `json:"cgroup,omitempty"`
+	Rlimits    map[string]uint64 `json:"rlimits,omitempty"`
+	Credential *credential       `json:"credential,omitempty"`
+	Umask      *int              `json:"umask,omitempty"`
+	Chroot     string            `json:"chroot,omitempty"`
+	Dir        string            `json:"dir,omitempty"`
+	ReadOnly   []string          `json:"read_only,omitempty"`
+	NoNewPrivs bool              `json:"no_new_privs,omitempty"`
+	ListenPid  bool              `json:"listen_pid,omitempty"`
+	Namespaces []string          `json:"-"`
+}
+
+//shim will return true if spec must be applied by exec shim
+func (e *execSpec) shim() bool {
+	return len(e.Cgroup) > 0 || len(e.Rlimits) > 0 || e.Umask != nil || len(e.Chroot) > 0 ||
+		len(e.ReadOnly) > 0 || e.NoNewPrivs || len(e.Namespaces) > 0 || e.ListenPid
+}
+
+//apply will apply spec to command, the command will be started by exec shim if needed
+func (e *execSpec) apply(cmd *exec.Cmd) (err error) {
+	if e.shim() {
+		err = applyShim(cmd, e)
+		return
+	}
+	if e.Credential != nil {
+		e.Credential.apply(cmd)
+	}
+	return
+}
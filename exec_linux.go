@@ -0,0 +1,184 @@
+package serviced
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+//execSpecEnv is the environment key to pass exec spec to exec shim
+const execSpecEnv = "SERVICED_EXEC_SPEC"
+
+var rlimitResources = map[string]int{
+	"nofile": unix.RLIMIT_NOFILE,
+	"nproc":  unix.RLIMIT_NPROC,
+	"core":   unix.RLIMIT_CORE,
+	"as":     unix.RLIMIT_AS,
+}
+
+var namespaceFlags = map[string]uintptr{
+	"pid":     syscall.CLONE_NEWPID,
+	"mount":   syscall.CLONE_NEWNS,
+	"network": syscall.CLONE_NEWNET,
+	"ipc":     syscall.CLONE_NEWIPC,
+}
+
+//shimRegistered is true when RunShim is called, so current executable can be used as exec shim
+var shimRegistered bool
+
+//RunShim will register current executable as exec shim of limits/sandbox/sockets, it must be called first in main,
+//it apply the exec spec and exec the service when current process is started as shim by manager, and never return in that case
+func RunShim() {
+	shimRegistered = true
+	spec := os.Getenv(execSpecEnv)
+	if len(spec) < 1 {
+		return
+	}
+	err := runShim(spec)
+	fmt.Fprintf(os.Stderr, "serviced exec shim fail with %v\n", err)
+	os.Exit(127)
+}
+
+//applyShim will replace the command to start by exec shim, the shim is current executable which applies spec and exec the service
+func applyShim(cmd *exec.Cmd, spec *execSpec) (err error) {
+	err = checkShimSupported("limits/sandbox/sockets")
+	if err != nil {
+		return
+	}
+	env := []string{}
+	for _, e := range cmd.Env {
+		if !strings.HasPrefix(e, execSpecEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	cmd.Path = "/proc/self/exe"
+	cmd.Env = append(env, execSpecEnv+"="+toJSON(spec))
+	if len(spec.Chroot) > 0 {
+		//the working directory is changed by shim after chroot
+		cmd.Dir = ""
+	}
+	if len(spec.Namespaces) > 0 {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		for _, ns := range spec.Namespaces {
+			cmd.SysProcAttr.Cloneflags |= namespaceFlags[ns]
+		}
+	}
+	return
+}
+
+//runShim will apply spec in current process and exec the service, it only return when fail
+func runShim(data string) (err error) {
+	spec := &execSpec{}
+	err = json.Unmarshal([]byte(data), spec)
+	if err != nil {
+		return
+	}
+	if len(spec.Cgroup) > 0 {
+		err = ioutil.WriteFile(filepath.Join(spec.Cgroup, "cgroup.procs"), []byte("0"), 0644)
+		if err != nil {
+			err = fmt.Errorf("join cgroup %v fail with %v", spec.Cgroup, err)
+			return
+		}
+	}
+	for name, val := range spec.Rlimits {
+		err = unix.Setrlimit(rlimitResources[name], &unix.Rlimit{Cur: val, Max: val})
+		if err != nil {
+			err = fmt.Errorf("set rlimit %v to %v fail with %v", name, val, err)
+			return
+		}
+	}
+	if len(spec.ReadOnly) > 0 {
+		//make all mount private to not propagate the read only bind to host
+		err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+		if err != nil {
+			err = fmt.Errorf("make mount private fail with %v", err)
+			return
+		}
+		for _, path := range spec.ReadOnly {
+			err = unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, "")
+			if err == nil {
+				err = unix.Mount("", path, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, "")
+			}
+			if err != nil {
+				err = fmt.Errorf("bind %v as read only fail with %v", path, err)
+				return
+			}
+		}
+	}
+	if len(spec.Chroot) > 0 {
+		err = unix.Chroot(spec.Chroot)
+		if err == nil {
+			err = unix.Chdir(spec.Dir)
+		}
+		if err != nil {
+			err = fmt.Errorf("chroot to %v fail with %v", spec.Chroot, err)
+			return
+		}
+	}
+	if spec.Umask != nil {
+		unix.Umask(*spec.Umask)
+	}
+	if spec.Credential != nil {
+		err = spec.Credential.set()
+		if err != nil {
+			return
+		}
+	}
+	if spec.NoNewPrivs {
+		err = unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+		if err != nil {
+			err = fmt.Errorf("set no new privileges fail with %v", err)
+			return
+		}
+	}
+	env := []string{}
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, execSpecEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	if spec.ListenPid {
+		//the socket activation require LISTEN_PID to be the service pid, which is same as shim after exec
+		env = append(env, fmt.Sprintf("LISTEN_PID=%v", os.Getpid()))
+	}
+	err = syscall.Exec(spec.Path, os.Args, env)
+	return
+}
+
+//set will switch current process to credential
+func (c *credential) set() (err error) {
+	if !c.NoSetGroups {
+		groups := []int{}
+		for _, gid := range c.Groups {
+			groups = append(groups, int(gid))
+		}
+		err = syscall.Setgroups(groups)
+	}
+	if err == nil {
+		err = syscall.Setgid(int(c.GID))
+	}
+	if err == nil {
+		err = syscall.Setuid(int(c.UID))
+	}
+	if err != nil {
+		err = fmt.Errorf("switch to uid %v gid %v fail with %v", c.UID, c.GID, err)
+	}
+	return
+}
+
+//checkShimSupported will check if exec shim is registered by RunShim
+func checkShimSupported(name string) (err error) {
+	if !shimRegistered {
+		err = fmt.Errorf("%v require exec shim, serviced.RunShim must be called first in main", name)
+	}
+	return
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package serviced
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+//RunShim will do nothing because exec shim is only supported on linux
+func RunShim() {
+}
+
+//applyShim will return error with the requested feature because exec shim is only supported on linux
+func applyShim(cmd *exec.Cmd, spec *execSpec) (err error) {
+	features := []string{}
+	if len(spec.Cgroup) > 0 || len(spec.Rlimits) > 0 {
+		features = append(features, "limits")
+	}
+	if spec.Umask != nil || len(spec.Chroot) > 0 || len(spec.ReadOnly) > 0 || spec.NoNewPrivs || len(spec.Namespaces) > 0 {
+		features = append(features, "sandbox")
+	}
+	if spec.ListenPid {
+		features = append(features, "sockets")
+	}
+	err = checkShimSupported(strings.Join(features, "/"))
+	return
+}
+
+//checkShimSupported will return error because exec shim is only supported on linux
+func checkShimSupported(name string) (err error) {
+	err = fmt.Errorf("%v is not supported on %v", name, runtime.GOOS)
+	return
+}
@@ -0,0 +1,141 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+//HistorySize is the max exit history kept on each service
+var HistorySize = 20
+
+//HistoryLines is the max stderr lines kept on each exit history
+var HistoryLines = 10
+
+//Exit is the exit history of service
+type Exit struct {
+	Key       string    `json:"key" yaml:"key"`
+	Group     string    `json:"group" yaml:"group"`
+	Name      string    `json:"name" yaml:"name"`
+	Instance  int       `json:"instance" yaml:"instance"`
+	Pid       int       `json:"pid" yaml:"pid"`
+	Start     time.Time `json:"start" yaml:"start"`
+	Stop      time.Time `json:"stop" yaml:"stop"`
+	Code      int       `json:"code" yaml:"code"`
+	Signal    string    `json:"signal,omitempty" yaml:"signal,omitempty"`
+	Requested bool      `json:"requested" yaml:"requested"`
+	Err       string    `json:"error,omitempty" yaml:"error,omitempty"`
+	Stderr    []string  `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+}
+
+//Crashed will return true if service is exited by itself with non-zero code or signal
+func (e *Exit) Crashed() bool {
+	return !e.Requested && (e.Code != 0 || len(e.Signal) > 0)
+}
+
+//newExit will create exit history from the exited running
+func newExit(running *Running, stopped time.Time, stderr []string) (exit *Exit) {
+	exit = &Exit{
+		Key:       running.Key,
+		Group:     running.Group.Name,
+		Name:      running.Service.Name,
+		Instance:  running.Instance,
+		Start:     running.Started,
+		Stop:      stopped,
+		Code:      exitCode(running.Err),
+		Requested: running.Requested,
+		Stderr:    stderr,
+	}
+	if _, ok := running.Err.(*exec.ExitError); running.Err != nil && !ok {
+		exit.Err = running.Err.Error()
+	}
+	if running.Cmd == nil {
+		//the service is fail to start
+		return
+	}
+	if running.Cmd.Process != nil {
+		exit.Pid = running.Cmd.Process.Pid
+	}
+	if state := running.Cmd.ProcessState; state != nil {
+		exit.Code = state.ExitCode()
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			exit.Signal = status.Signal().String()
+		}
+	}
+	return
+}
+
+//addHistory will append exit history of service and keep the last HistorySize, it must be called with locker
+func (m *Manager) addHistory(exit *Exit) {
+	key := exit.Group + "/" + exit.Name
+	history := append(m.history[key], exit)
+	if len(history) > HistorySize {
+		history = append([]*Exit{}, history[len(history)-HistorySize:]...)
+	}
+	m.history[key] = history
+}
+
+//lastExit will return the last exit history of service instance by running key, it must be called with locker
+func (m *Manager) lastExit(group, name, key string) (exit *Exit) {
+	history := m.history[group+"/"+name]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Key == key {
+			exit = history[i]
+			break
+		}
+	}
+	return
+}
+
+//History will return the exit history matched by pattern, pattern is all, group name or group/service with glob,
+//the history is sorted by service and exit time
+func (m *Manager) History(pattern string) (history []*Exit) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	keys := []string{}
+	for key := range m.history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, exit := range m.history[key] {
+			if matchTarget(pattern, exit.Group, exit.Key) {
+				history = append(history, exit)
+			}
+		}
+	}
+	return
+}
+
+//WriteHistory will write exit history by format, format is table/wide/json/yaml, the tmpl is go template which is executed on each history if it is not empty
+func WriteHistory(out io.Writer, history []*Exit, format, tmpl string) (err error) {
+	return writeItems(out, history, format, tmpl, func(writer *tabwriter.Writer, wide bool) {
+		if wide {
+			fmt.Fprintf(writer, "STOP\tNAME\tGROUP\tPID\tEXIT\tREQUESTED\tDURATION\tSTART\tERROR\tSTDERR\n")
+		} else {
+			fmt.Fprintf(writer, "STOP\tNAME\tGROUP\tPID\tEXIT\tREQUESTED\tDURATION\n")
+		}
+		for _, e := range history {
+			name := strings.TrimPrefix(e.Key, e.Group+"/")
+			exit := fmt.Sprintf("%v", e.Code)
+			if len(e.Signal) > 0 {
+				exit = "signal:" + e.Signal
+			}
+			duration := e.Stop.Sub(e.Start).Round(time.Millisecond)
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v", e.Stop.Format("2006-01-02 15:04:05"), cell(name), cell(e.Group), e.Pid, exit, e.Requested, duration)
+			if wide {
+				stderr := ""
+				if len(e.Stderr) > 0 {
+					stderr = e.Stderr[len(e.Stderr)-1]
+				}
+				fmt.Fprintf(writer, "\t%v\t%v\t%v", e.Start.Format("2006-01-02 15:04:05"), cell(e.Err), cell(stderr))
+			}
+			fmt.Fprintf(writer, "\n")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package serviced
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineTail(t *testing.T) {
+	tail := newLineTail(2)
+	tail.Write([]byte("a\nb"))
+	tail.Write([]byte("c\r\nd\ne"))
+	if lines := tail.Lines(); len(lines) != 2 || lines[0] != "d" || lines[1] != "e" {
+		t.Errorf("%v", lines)
+		return
+	}
+	tail.Write([]byte("\n"))
+	if lines := tail.Lines(); len(lines) != 2 || lines[0] != "d" || lines[1] != "e" {
+		t.Errorf("%v", lines)
+		return
+	}
+}
+
+func TestHistory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["history"] = Group{
+		Name:     "history",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "crash", Path: "/bin/sh", Args: []string{"-c", "echo line1 >&2; echo line2 >&2; exit 3"}},
+			{Name: "sleep", Path: "/bin/sleep", Args: []string{"10"}},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "history")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("history/crash", WaitExited, 3*time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	m.StopService("history", "sleep")
+	history := m.History("history")
+	if len(history) != 2 {
+		t.Errorf("%v", toJSON(history))
+		return
+	}
+	crash, sleep := history[0], history[1]
+	if crash.Code != 3 || crash.Requested || !crash.Crashed() || len(crash.Stderr) != 2 || crash.Stderr[1] != "line2" || crash.Pid < 1 {
+		t.Errorf("%v", toJSON(crash))
+		return
+	}
+	if !sleep.Requested || sleep.Crashed() || len(sleep.Signal) < 1 || sleep.Code != -1 {
+		t.Errorf("%v", toJSON(sleep))
+		return
+	}
+	if status := m.Status("history/crash"); len(status) != 1 || status[0].LastRun == nil || status[0].LastCode != 3 {
+		t.Errorf("%v", toJSON(status))
+		return
+	}
+	//bounded
+	HistorySize = 1
+	defer func() { HistorySize = 20 }()
+	m.Start(ioutil.Discard, "history/crash")
+	m.Wait("history/crash", WaitExited, 3*time.Second)
+	if len(m.History("history/crash")) != 1 {
+		t.Errorf("%v", toJSON(m.History("history/crash")))
+		return
+	}
+	//output
+	buffer := bytes.NewBuffer(nil)
+	err = WriteHistory(buffer, history, FormatWide, "")
+	if err != nil || !strings.Contains(buffer.String(), "signal:") || !strings.Contains(buffer.String(), "line2") {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	buffer.Reset()
+	err = WriteHistory(buffer, history, "", "{{.Name}} {{.Code}}")
+	if err != nil || buffer.String() != "crash 3\nsleep -1\n" {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	//stderr file is written directly and the last lines is read from file
+	ioutil.WriteFile("test-history.log", []byte("old\n"), 0644)
+	defer os.Remove("test-history.log")
+	m.Groups["history"].Services[0].Stderr = "test-history.log"
+	m.Start(ioutil.Discard, "history/crash")
+	m.Wait("history/crash", WaitExited, 3*time.Second)
+	if crash := m.History("history/crash")[0]; len(crash.Stderr) != 2 || crash.Stderr[0] != "line1" || crash.Stderr[1] != "line2" {
+		t.Errorf("%v", toJSON(crash))
+		return
+	}
+}
@@ -0,0 +1,92 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//HookPreStart is the hook name run before start
+	HookPreStart = "pre_start"
+	//HookPostStart is the hook name run after started
+	HookPostStart = "post_start"
+	//HookPreStop is the hook name run before stop
+	HookPreStop = "pre_stop"
+	//HookPostStop is the hook name run after stopped
+	HookPostStop = "post_stop"
+)
+
+//Hooks is struct to record lifecycle hook commands, each command is run by shell
+type Hooks struct {
+	PreStart  []string `json:"pre_start"`
+	PostStart []string `json:"post_start"`
+	PreStop   []string `json:"pre_stop"`
+	PostStop  []string `json:"post_stop"`
+}
+
+//commands will return hook commands by name
+func (h *Hooks) commands(name string) (commands []string) {
+	switch name {
+	case HookPreStart:
+		commands = h.PreStart
+	case HookPostStart:
+		commands = h.PostStart
+	case HookPreStop:
+		commands = h.PreStop
+	case HookPostStop:
+		commands = h.PostStop
+	}
+	return
+}
+
+//hookRunner is the runner to run hook commands with same environment
+type hookRunner struct {
+	Prefix string
+	Values map[string]interface{}
+	Dir    string
+	Env    []string
+	Cred   *credential
+	//Out is the writer to receive hook output, it will be logged if nil
+	Out io.Writer
+}
+
+//run will run all hook commands by name, it will stop on first fail
+func (h *hookRunner) run(hooks *Hooks, name string) (err error) {
+	for _, command := range hooks.commands(name) {
+		command = envReplaceEmpty(h.Values, command, false)
+		log.Infof("%v run %v hook by %v", h.Prefix, name, command)
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("/bin/sh", "-c", command)
+		}
+		cmd.Dir = h.Dir
+		cmd.Env = h.Env
+		if h.Cred != nil {
+			h.Cred.apply(cmd)
+		}
+		if h.Out != nil {
+			fmt.Fprintf(h.Out, "[%v] %v\n", name, command)
+			cmd.Stdout = h.Out
+			cmd.Stderr = h.Out
+			err = cmd.Run()
+		} else {
+			var output []byte
+			output, err = cmd.CombinedOutput()
+			if len(output) > 0 {
+				log.Infof("%v %v hook output:\n%v", h.Prefix, name, strings.TrimSpace(string(output)))
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("%v hook %v fail with %v", name, command, err)
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,138 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	os.Remove("hooks.log")
+	defer os.Remove("hooks.log")
+	group := &Group{
+		Name:     "hooks",
+		Filename: "test-service.json",
+		Hooks: Hooks{
+			PreStart: []string{"echo group-pre-start >> hooks.log"},
+			PostStop: []string{"echo group-post-stop >> hooks.log"},
+		},
+		Services: []Service{
+			{
+				Name:   "migrate",
+				Path:   "/bin/sh",
+				Args:   []string{"-c", "echo migrate"},
+				Type:   ServiceOneshot,
+				Stdout: "hooks.log",
+				Hooks: Hooks{
+					PostStart: []string{"echo migrate-post-start"},
+				},
+			},
+			{
+				Name:   "server",
+				Path:   "/bin/sleep",
+				Args:   []string{"10"},
+				Stdout: "hooks.log",
+				Hooks: Hooks{
+					PreStart: []string{"echo server-pre-start"},
+					PreStop:  []string{"echo server-pre-stop"},
+					PostStop: []string{"echo server-post-stop"},
+				},
+			},
+		},
+	}
+	m := NewManager()
+	err := m.startGroup(ioutil.Discard, group)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.StopGroup("hooks")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, _ := ioutil.ReadFile("hooks.log")
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if !strings.HasPrefix(line, "[") {
+			lines = append(lines, line)
+		}
+	}
+	if strings.Join(lines, ",") != "group-pre-start,migrate,migrate-post-start,server-pre-start,server-pre-stop,server-post-stop,group-post-stop" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	//oneshot fail
+	group.Services[0].Args = []string{"-c", "exit 1"}
+	err = m.startGroup(ioutil.Discard, group)
+	if err == nil {
+		t.Error("error")
+		return
+	}
+	if len(m.running) > 0 {
+		t.Error("error")
+		return
+	}
+	//pre start fail
+	group.Services[0].Hooks.PreStart = []string{"exit 1"}
+	err = m.StartService(group, &group.Services[0])
+	if err == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestGroupHookEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	dir, _ := ioutil.TempDir("", "serviced")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "serviced-hook-test"), []byte("#!/bin/sh\necho $1 >> hooks-env.log\n"), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+path)
+	defer os.Setenv("PATH", path)
+	os.Remove("hooks-env.log")
+	defer os.Remove("hooks-env.log")
+	group := &Group{
+		Name:     "hooks",
+		Filename: "test-service.json",
+		Hooks: Hooks{
+			PreStart:  []string{"serviced-hook-test pre-start"},
+			PostStart: []string{"serviced-hook-test post-start"},
+		},
+		Services: []Service{
+			{Name: "fail", Path: "/not/exist"},
+			{Name: "server", Path: "/bin/sleep", Args: []string{"10"}},
+		},
+	}
+	//post start is not run when any service is fail
+	m := NewManager()
+	err := m.startGroup(ioutil.Discard, group)
+	if err == nil || !strings.Contains(err.Error(), "fail") {
+		t.Error(err)
+		return
+	}
+	m.StopGroup("hooks")
+	if data, _ := ioutil.ReadFile("hooks-env.log"); string(data) != "pre-start\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	group.Services = group.Services[1:]
+	err = m.startGroup(ioutil.Discard, group)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	m.StopGroup("hooks")
+	if data, _ := ioutil.ReadFile("hooks-env.log"); string(data) != "pre-start\npre-start\npost-start\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+}
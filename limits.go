@@ -0,0 +1,100 @@
+package serviced
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Limits is struct to record service resource limits
+type Limits struct {
+	NoFile *uint64 `json:"nofile"`
+	NProc  *uint64 `json:"nproc"`
+	Core   *uint64 `json:"core"`
+	AS     *uint64 `json:"as"`
+	Memory string  `json:"memory"`
+	CPU    float64 `json:"cpu"`
+	Pids   int64   `json:"pids"`
+}
+
+//rlimits will return all configured rlimit by name
+func (l *Limits) rlimits() (rlimits map[string]uint64) {
+	rlimits = map[string]uint64{}
+	for name, val := range map[string]*uint64{"nofile": l.NoFile, "nproc": l.NProc, "core": l.Core, "as": l.AS} {
+		if val != nil {
+			rlimits[name] = *val
+		}
+	}
+	return
+}
+
+//cgroup will return true if any cgroup limit is configured
+func (l *Limits) cgroup() bool {
+	return len(l.Memory) > 0 || l.CPU > 0 || l.Pids > 0
+}
+
+//check will check limits configure
+func (l *Limits) check() (err error) {
+	if len(l.Memory) > 0 {
+		_, err = parseSize(l.Memory)
+		if err != nil {
+			err = fmt.Errorf("limits memory %v", err)
+			return
+		}
+	}
+	if l.CPU < 0 || l.Pids < 0 {
+		err = fmt.Errorf("limits cpu/pids must be positive")
+		return
+	}
+	if len(l.rlimits()) > 0 || l.cgroup() {
+		err = checkShimSupported("limits")
+	}
+	return
+}
+
+//parseSize will parse size string like 1024, 512K, 64M, 2G or max to bytes, max is returned as -1
+func parseSize(size string) (bytes int64, err error) {
+	size = strings.TrimSpace(size)
+	if size == "max" {
+		bytes = -1
+		return
+	}
+	unit := int64(1)
+	if len(size) > 0 {
+		switch strings.ToUpper(size[len(size)-1:]) {
+		case "K":
+			unit = 1 << 10
+		case "M":
+			unit = 1 << 20
+		case "G":
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			size = size[:len(size)-1]
+		}
+	}
+	bytes, err = strconv.ParseInt(size, 10, 64)
+	if err == nil && bytes < 0 {
+		err = fmt.Errorf("size must be positive")
+	}
+	if err != nil {
+		err = fmt.Errorf("parse size %v fail with %v", size, err)
+		return
+	}
+	bytes *= unit
+	return
+}
+
+//formatSize will format bytes to human readable string
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%vB", bytes)
+	}
+}
@@ -0,0 +1,66 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for size, bytes := range map[string]int64{"100": 100, "1K": 1024, "2m": 2 << 20, "1G": 1 << 30, "max": -1} {
+		val, err := parseSize(size)
+		if err != nil || val != bytes {
+			t.Errorf("%v,%v,%v", size, val, err)
+			return
+		}
+	}
+	for _, size := range []string{"", "x", "-1", "1T"} {
+		if _, err := parseSize(size); err == nil {
+			t.Errorf("%v", size)
+			return
+		}
+	}
+	if formatSize(100) != "100B" || formatSize(1536) != "1.5K" || formatSize(2<<20) != "2.0M" {
+		t.Error("error")
+		return
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	nofile := uint64(100)
+	group := &Group{
+		Name:     "limits",
+		Filename: "test-service.json",
+	}
+	service := &Service{
+		Name:   "ulimit",
+		Path:   "/bin/sh",
+		Args:   []string{"-c", "ulimit -n"},
+		Stdout: "ulimit.log",
+		Limits: &Limits{NoFile: &nofile},
+	}
+	os.Remove("ulimit.log")
+	defer os.Remove("ulimit.log")
+	m := NewManager()
+	err := m.StartService(group, service)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	m.locker.Lock()
+	running := m.running["limits/ulimit"]
+	m.locker.Unlock()
+	if running != nil {
+		running.Waiter.Wait()
+	}
+	data, _ := ioutil.ReadFile("ulimit.log")
+	if strings.TrimSpace(string(data)) != "100" {
+		t.Errorf("%v", string(data))
+		return
+	}
+}
@@ -0,0 +1,384 @@
+package serviced
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//LogSyslog is the log driver to send line to syslog by RFC5424
+	LogSyslog = "syslog"
+	//LogJSON is the log driver to write line to file as json lines
+	LogJSON = "json"
+	//LogTCP is the log driver to send line to tcp server
+	LogTCP = "tcp"
+)
+
+//LogMaxLine is the max line length sent to log driver, the longer line is split
+var LogMaxLine = 64 * 1024
+
+//LogQueueSize is the max line waiting to send on log driver, the line is dropped when queue is full
+var LogQueueSize = 1024
+
+var syslogFacility = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+//LogDriver is the log driver configure to ship output line, network/address is used by syslog/tcp,
+//path is used by json, facility is syslog facility, tag is syslog app name, format is text/json on tcp
+type LogDriver struct {
+	Type     string `json:"type"`
+	Network  string `json:"network,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Facility string `json:"facility,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Format   string `json:"format,omitempty"`
+}
+
+//check will check log driver configure
+func (d *LogDriver) check() (err error) {
+	switch d.Type {
+	case LogSyslog:
+		switch d.Network {
+		case "", "unix", "unixgram", "udp", "tcp":
+		default:
+			err = fmt.Errorf("syslog network must be unix, unixgram, udp or tcp")
+		}
+		if _, ok := syslogFacility[d.Facility]; err == nil && len(d.Facility) > 0 && !ok {
+			err = fmt.Errorf("syslog facility %v is not supported", d.Facility)
+		}
+		if err == nil && len(d.Address) < 1 && d.Network != "" && d.Network != "unix" && d.Network != "unixgram" {
+			err = fmt.Errorf("syslog address is required on %v", d.Network)
+		}
+	case LogJSON:
+		if len(d.Path) < 1 {
+			err = fmt.Errorf("json log path is required")
+		}
+	case LogTCP:
+		if len(d.Address) < 1 {
+			err = fmt.Errorf("tcp log address is required")
+		}
+		if err == nil && len(d.Format) > 0 && d.Format != "text" && d.Format != "json" {
+			err = fmt.Errorf("tcp log format must be text or json")
+		}
+	default:
+		err = fmt.Errorf("log driver type must be %v, %v or %v", LogSyslog, LogJSON, LogTCP)
+	}
+	return
+}
+
+//logLine is the output line tagged by manager
+type logLine struct {
+	Time     time.Time `json:"time"`
+	Group    string    `json:"group,omitempty"`
+	Service  string    `json:"service"`
+	Instance int       `json:"instance"`
+	Pid      int       `json:"pid,omitempty"`
+	Stream   string    `json:"stream"`
+	Line     string    `json:"line"`
+}
+
+//logSink is the log driver sink to ship line
+type logSink interface {
+	WriteLine(line *logLine) error
+	Close() error
+}
+
+//open will open the log sink, path is the json file path which is resolved by caller, openFile is used to open json file
+func (d *LogDriver) open(path string, openFile func(path string) (*os.File, error)) (sink logSink, err error) {
+	switch d.Type {
+	case LogSyslog:
+		network, address := d.Network, d.Address
+		if len(network) < 1 {
+			network = "unixgram"
+		}
+		if len(address) < 1 {
+			address = "/dev/log"
+		}
+		facility := syslogFacility["daemon"]
+		if len(d.Facility) > 0 {
+			facility = syslogFacility[d.Facility]
+		}
+		hostname, _ := os.Hostname()
+		sink = &netSink{
+			Network: network,
+			Address: address,
+			Framing: network == "tcp" || network == "unix",
+			Format: func(line *logLine) []byte {
+				return formatSyslog(facility, hostname, d.Tag, line)
+			},
+		}
+	case LogJSON:
+		var file *os.File
+		file, err = openFile(path)
+		if err != nil {
+			return
+		}
+		sink = &jsonSink{file: file}
+	case LogTCP:
+		format := formatText
+		if d.Format == "json" {
+			format = func(line *logLine) []byte { return []byte(toJSON(line) + "\n") }
+		}
+		sink = &netSink{
+			Network: "tcp",
+			Address: d.Address,
+			Format:  format,
+		}
+	default:
+		err = fmt.Errorf("log driver type %v is not supported", d.Type)
+		return
+	}
+	sink = newAsyncSink(sink, LogQueueSize)
+	return
+}
+
+//formatText will format line as text like 2006-01-02T15:04:05.000Z07:00 group/service@0 stdout line
+func formatText(line *logLine) []byte {
+	name := line.Service
+	if len(line.Group) > 0 {
+		name = line.Group + "/" + line.Service
+	}
+	return []byte(fmt.Sprintf("%v %v@%v %v %v\n", line.Time.Format("2006-01-02T15:04:05.000Z07:00"), name, line.Instance, line.Stream, line.Line))
+}
+
+//formatSyslog will format line as RFC5424 message, stderr is error severity and others is info
+func formatSyslog(facility int, hostname, tag string, line *logLine) []byte {
+	severity := 6
+	switch line.Stream {
+	case "stderr", "error", "fatal", "panic":
+		severity = 3
+	case "warning":
+		severity = 4
+	case "debug", "trace":
+		severity = 7
+	}
+	if len(hostname) < 1 {
+		hostname = "-"
+	}
+	if len(tag) < 1 {
+		tag = line.Service
+		if len(line.Group) > 0 {
+			tag = line.Group + "/" + line.Service
+		}
+	}
+	procid := "-"
+	if line.Pid > 0 {
+		procid = fmt.Sprintf("%v", line.Pid)
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return []byte(fmt.Sprintf(`<%v>1 %v %v %v %v %v [serviced@32473 group="%v" service="%v" instance="%v"] %v`,
+		facility*8+severity, line.Time.Format("2006-01-02T15:04:05.000000Z07:00"), hostname, syslogName(tag, 48), procid,
+		syslogName(line.Stream, 32), escaper.Replace(line.Group), escaper.Replace(line.Service), line.Instance, line.Line))
+}
+
+//syslogName will replace the not printable and space char, and limit the length
+func syslogName(name string, max int) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) < 1 {
+		return "-"
+	}
+	if len(name) > max {
+		name = name[:max]
+	}
+	return name
+}
+
+//jsonSink will write line as json line to file
+type jsonSink struct {
+	file *os.File
+}
+
+func (j *jsonSink) WriteLine(line *logLine) (err error) {
+	data, err := json.Marshal(line)
+	if err == nil {
+		_, err = j.file.Write(append(data, '\n'))
+	}
+	return
+}
+
+func (j *jsonSink) Close() (err error) {
+	err = j.file.Close()
+	return
+}
+
+//netSink will send line to network, it is connected on first line and reconnected on fail after one second,
+//the line is prefixed with length by RFC6587 octet counting when framing is true
+type netSink struct {
+	Network  string
+	Address  string
+	Framing  bool
+	Format   func(line *logLine) []byte
+	conn     net.Conn
+	lastDial time.Time
+}
+
+func (n *netSink) WriteLine(line *logLine) (err error) {
+	if n.conn == nil {
+		if time.Since(n.lastDial) < time.Second {
+			err = fmt.Errorf("%v is not connected", n.Address)
+			return
+		}
+		n.lastDial = time.Now()
+		n.conn, err = net.DialTimeout(n.Network, n.Address, 3*time.Second)
+		if err != nil {
+			return
+		}
+	}
+	data := n.Format(line)
+	if n.Framing {
+		data = append([]byte(fmt.Sprintf("%v ", len(data))), data...)
+	}
+	n.conn.SetWriteDeadline(time.Now().Add(3 * time.Second))
+	_, err = n.conn.Write(data)
+	if err != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return
+}
+
+func (n *netSink) Close() (err error) {
+	if n.conn != nil {
+		err = n.conn.Close()
+		n.conn = nil
+	}
+	return
+}
+
+//asyncSink will write line to sink in background, so the service output is not blocked by slow sink
+type asyncSink struct {
+	sink    logSink
+	queue   chan *logLine
+	done    chan int
+	dropped int
+	failed  int
+	closed  bool
+	locker  sync.Mutex
+}
+
+func newAsyncSink(sink logSink, size int) (async *asyncSink) {
+	async = &asyncSink{
+		sink:  sink,
+		queue: make(chan *logLine, size),
+		done:  make(chan int),
+	}
+	go async.loop()
+	return
+}
+
+func (a *asyncSink) loop() {
+	for line := range a.queue {
+		if err := a.sink.WriteLine(line); err != nil {
+			a.locker.Lock()
+			a.failed++
+			a.locker.Unlock()
+		}
+	}
+	close(a.done)
+}
+
+//WriteLine will queue the line, it is dropped when queue is full or sink is closed
+func (a *asyncSink) WriteLine(line *logLine) (err error) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+	if a.closed {
+		err = fmt.Errorf("log sink is closed")
+		return
+	}
+	select {
+	case a.queue <- line:
+	default:
+		a.dropped++
+		err = fmt.Errorf("log queue is full")
+	}
+	return
+}
+
+//Close will wait all queued line is sent and close the sink, it return error when any line is dropped or fail
+func (a *asyncSink) Close() (err error) {
+	a.locker.Lock()
+	a.closed = true
+	close(a.queue)
+	a.locker.Unlock()
+	<-a.done
+	err = a.sink.Close()
+	a.locker.Lock()
+	if err == nil && (a.dropped > 0 || a.failed > 0) {
+		err = fmt.Errorf("%v line is dropped and %v line is fail", a.dropped, a.failed)
+	}
+	a.locker.Unlock()
+	return
+}
+
+//logStream will return the writer to ship each line to sink with tags, pid is called on each line because the output may be written before pid is known
+func logStream(sink logSink, tags logLine, pid func() int) (writer *lineWriter) {
+	writer = newLineWriter(LogMaxLine, func(line []byte) {
+		l := tags
+		l.Time = time.Now()
+		l.Pid = pid()
+		l.Line = string(line)
+		sink.WriteLine(&l)
+	})
+	return
+}
+
+//LogHook is the logrus hook to ship daemon log to log driver
+type LogHook struct {
+	sink logSink
+}
+
+//NewLogHook will create logrus hook by log driver
+func NewLogHook(driver *LogDriver) (hook *LogHook, err error) {
+	err = driver.check()
+	if err != nil {
+		return
+	}
+	sink, err := driver.open(driver.Path, func(path string) (*os.File, error) {
+		return openLogFile(path, DefaultLogFileMode, DefaultLogDirMode, nil)
+	})
+	if err == nil {
+		hook = &LogHook{sink: sink}
+	}
+	return
+}
+
+//Levels will return all levels
+func (h *LogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+//Fire will send each line of entry message to sink
+func (h *LogHook) Fire(entry *log.Entry) (err error) {
+	for _, line := range strings.Split(strings.TrimRight(entry.Message, "\n"), "\n") {
+		h.sink.WriteLine(&logLine{
+			Time:    entry.Time,
+			Service: "serviced",
+			Pid:     os.Getpid(),
+			Stream:  entry.Level.String(),
+			Line:    line,
+		})
+	}
+	return
+}
+
+//Close will close the sink
+func (h *LogHook) Close() (err error) {
+	err = h.sink.Close()
+	return
+}
@@ -0,0 +1,132 @@
+package serviced
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := []string{}
+	writer := newLineWriter(4, func(line []byte) { lines = append(lines, string(line)) })
+	writer.Write([]byte("ab"))
+	writer.Write([]byte("c\r\nabcdefghi\nxy"))
+	writer.Write([]byte("z"))
+	writer.Flush()
+	if strings.Join(lines, ",") != "abc,abcd,efgh,i,xyz" {
+		t.Errorf("%v", lines)
+		return
+	}
+}
+
+func TestLogDriver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-log.jsonl")
+	tcp, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer tcp.Close()
+	udp, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer udp.Close()
+	m := NewManager()
+	m.init()
+	script := "echo out1; echo err1 >&2; printf out2"
+	m.Groups["log"] = Group{
+		Name:     "log",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "json", Path: "/bin/sh", Args: []string{"-c", script}, Type: ServiceOneshot, LogDriver: &LogDriver{Type: LogJSON, Path: "test-log.jsonl"}},
+			{Name: "tcp", Path: "/bin/sh", Args: []string{"-c", script}, Type: ServiceOneshot, LogDriver: &LogDriver{Type: LogTCP, Address: tcp.Addr().String()}},
+			{Name: "syslog", Path: "/bin/sh", Args: []string{"-c", script}, Type: ServiceOneshot, LogDriver: &LogDriver{Type: LogSyslog, Network: "udp", Address: udp.LocalAddr().String(), Tag: "app"}},
+		},
+	}
+	received := make(chan string, 10)
+	go func() {
+		conn, err := tcp.Accept()
+		if err != nil {
+			return
+		}
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			received <- line
+		}
+	}()
+	err := m.StartGroup(ioutil.Discard, "log")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	//json
+	data, _ := ioutil.ReadFile("test-log.jsonl")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	streams := map[string]*logLine{}
+	for _, line := range lines {
+		l := &logLine{}
+		json.Unmarshal([]byte(line), l)
+		streams[l.Line] = l
+	}
+	if len(lines) != 3 || streams["out1"].Stream != "stdout" || streams["err1"].Stream != "stderr" || streams["out2"].Group != "log" || streams["out2"].Service != "json" || streams["out1"].Pid < 1 {
+		t.Errorf("%v", string(data))
+		return
+	}
+	//tcp
+	all := ""
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-received:
+			all += line
+		case <-time.After(3 * time.Second):
+			t.Errorf("timeout on %v", all)
+			return
+		}
+	}
+	for _, expect := range []string{" log/tcp@0 stdout out1\n", " log/tcp@0 stderr err1\n", " log/tcp@0 stdout out2\n"} {
+		if !strings.Contains(all, expect) {
+			t.Errorf("%v not in %v", expect, all)
+			return
+		}
+	}
+	//syslog
+	buffer := make([]byte, 1024)
+	udp.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := udp.ReadFrom(buffer)
+	if err != nil || !strings.HasPrefix(string(buffer[:n]), "<") || !strings.Contains(string(buffer[:n]), `>1 `) || !strings.Contains(string(buffer[:n]), ` app `) || !strings.Contains(string(buffer[:n]), `[serviced@32473 group="log" service="syslog" instance="0"]`) {
+		t.Errorf("%v,%v", err, string(buffer[:n]))
+		return
+	}
+	//daemon log
+	defer os.Remove("test-daemon.jsonl")
+	hook, err := NewLogHook(&LogDriver{Type: LogJSON, Path: "test-daemon.jsonl"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.AddHook(hook)
+	m.logHook = hook
+	log.Warnf("daemon\nlog")
+	m.StopLog()
+	data, _ = ioutil.ReadFile("test-daemon.jsonl")
+	if !strings.Contains(string(data), `"stream":"warning","line":"daemon"`) || !strings.Contains(string(data), `"line":"log"`) {
+		t.Errorf("%v", string(data))
+		return
+	}
+	//check
+	for _, driver := range []*LogDriver{{Type: "xx"}, {Type: LogJSON}, {Type: LogTCP}, {Type: LogTCP, Address: "x", Format: "xx"}, {Type: LogSyslog, Network: "xx"}, {Type: LogSyslog, Facility: "xx"}, {Type: LogSyslog, Network: "udp"}} {
+		if driver.check() == nil {
+			t.Errorf("%v", toJSON(driver))
+			return
+		}
+	}
+}
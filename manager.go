@@ -9,9 +9,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -19,34 +22,85 @@ import (
 const (
 	//StateRunning is the service running state
 	StateRunning = 100
+	//StateReady is the service running and passed readiness probe state
+	StateReady = 200
 	//StateStopped is the service stopped state
 	StateStopped = 300
 )
 
 //Running is running struct
 type Running struct {
-	State   int
-	Cmd     *exec.Cmd
-	Group   *Group
-	Service *Service
-	Err     error
-	Waiter  sync.WaitGroup
+	State    int
+	Key      string
+	Instance int
+	Cmd      *exec.Cmd
+	Group    *Group
+	Service  *Service
+	Err      error
+	Cgroup   string
+	Started  time.Time
+	Waiter   sync.WaitGroup
+	//Requested is true when the service is stopped by manager
+	Requested   bool
+	hook        *hookRunner
+	stdin       *os.File
+	stdinLocker sync.Mutex
+	output      *serviceOutput
+	ready       chan int
+	exited      chan int
 }
 
 //Manager is service manager
 type Manager struct {
 	Config
-	TempDir string
-	running map[string]*Running
-	locker  sync.RWMutex
-	console net.Listener
+	TempDir     string
+	ConsoleAddr string
+	//Dev is the development mode which is restarting service on watched files changed
+	Dev bool
+	//Foreground is true when manager is running in terminal, the service is started in new process group to not receive terminal signal
+	Foreground bool
+	//StopSignal is the signal sent to service on stopping, the service is killed after StopTimeout, it is killed directly when nil,
+	//it must be changed with locker when any service is running
+	StopSignal os.Signal
+	//Output is the writer to copy the output of all service with key prefix
+	Output io.Writer
+	//Color is true to colorize the key prefix on Output
+	Color     bool
+	running   map[string]*Running
+	schedules map[string]*Schedule
+	scales    map[string]int
+	started   map[string]bool
+	exits     map[string]*Running
+	history   map[string][]*Exit
+	restarts  map[string]*time.Timer
+	restarted map[string][]time.Time
+	sockets   map[string]*serviceSockets
+	ports     map[string]map[string]int
+	proxies   map[string]*groupProxy
+	watchers  map[string]*serviceWatcher
+	changed   chan int
+	locker    sync.RWMutex
+	console   net.Listener
+	logHook   *LogHook
 }
 
 //NewManager will return new manager
 func NewManager() (manager *Manager) {
 	manager = &Manager{
-		running: map[string]*Running{},
-		locker:  sync.RWMutex{},
+		running:   map[string]*Running{},
+		schedules: map[string]*Schedule{},
+		scales:    map[string]int{},
+		started:   map[string]bool{},
+		exits:     map[string]*Running{},
+		history:   map[string][]*Exit{},
+		restarts:  map[string]*time.Timer{},
+		restarted: map[string][]time.Time{},
+		sockets:   map[string]*serviceSockets{},
+		ports:     map[string]map[string]int{},
+		proxies:   map[string]*groupProxy{},
+		watchers:  map[string]*serviceWatcher{},
+		changed:   make(chan int),
+		locker:    sync.RWMutex{},
 	}
 	return
 }
@@ -59,7 +113,26 @@ func (m *Manager) Bootstrap() (err error) {
 		log.Errorf("load configure from %v fail with %v", m.Filename, err)
 		return
 	}
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if m.LogDriver != nil && m.logHook == nil {
+		driver := *m.LogDriver
+		if len(driver.Path) > 0 && !filepath.IsAbs(driver.Path) {
+			driver.Path = filepath.Join(filepath.Dir(m.Filename), driver.Path)
+		}
+		m.logHook, err = NewLogHook(&driver)
+		if err != nil {
+			log.Errorf("open log driver fail with %v", err)
+			return
+		}
+		log.AddHook(m.logHook)
+	}
+	consoleAddr := m.ConsoleAddr
+	if len(consoleAddr) < 1 {
+		consoleAddr = m.Config.Console
+	}
+	if len(consoleAddr) < 1 {
+		consoleAddr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", consoleAddr)
 	if err != nil {
 		log.Errorf("start console listen fail with %v", err)
 		return
@@ -93,7 +166,13 @@ func (m *Manager) procConsole(listener net.Listener) (err error) {
 func (m *Manager) procConn(conn net.Conn) {
 	defer conn.Close()
 	reader := bufio.NewReader(conn)
+	var peeking chan int
 	for {
+		if peeking != nil {
+			//the reader is used by closed checking of last wait command
+			<-peeking
+			peeking = nil
+		}
 		cmd, err := reader.ReadBytes('\n')
 		if err != nil {
 			break
@@ -106,22 +185,18 @@ func (m *Manager) procConn(conn net.Conn) {
 		}
 		switch parts[0] {
 		case "start":
-			switch parts[1] {
-			case "all":
-				fmt.Fprintf(conn, "all service is starting\n")
-				err = m.StartAll(conn)
-			default:
-				fmt.Fprintf(conn, "%v service is starting\n", parts[1])
-				err = m.StartGroup(conn, parts[1])
-			}
+			fmt.Fprintf(conn, "%v service is starting\n", parts[1])
+			err = m.Start(conn, parts[1])
 		case "stop":
-			switch parts[1] {
-			case "all":
-				fmt.Fprintf(conn, "all service is stopping\n")
-				err = m.StopAll()
-			default:
-				fmt.Fprintf(conn, "%v service is stopping\n", parts[1])
-				err = m.StopGroup(parts[1])
+			fmt.Fprintf(conn, "%v service is stopping\n", parts[1])
+			err = m.Stop(parts[1])
+		case "restart":
+			if len(parts) > 2 && parts[2] == "--rolling" {
+				fmt.Fprintf(conn, "%v service is rolling restarting\n", parts[1])
+				err = m.RollingRestart(conn, parts[1])
+			} else {
+				fmt.Fprintf(conn, "%v service is restarting\n", parts[1])
+				err = m.Restart(conn, parts[1])
 			}
 		case "add":
 			var group Group
@@ -135,18 +210,99 @@ func (m *Manager) procConn(conn net.Conn) {
 			var group Group
 			group, err = m.Remove(parts[1])
 			if err == nil {
+				m.CloseSockets(group.Name)
+				m.CloseProxy(group.Name)
+				m.releasePorts(func(key string) bool { return strings.HasPrefix(key, group.Name+"/") })
 				fmt.Fprintf(conn, "remove group %v success with %v service\n", group.Name, len(group.Services))
 			} else {
 				fmt.Fprintf(conn, "remove group %v fail with %v\n", parts[1], err)
 			}
+		case "scale":
+			var count int
+			if len(parts) > 2 {
+				count, err = strconv.Atoi(parts[2])
+			} else {
+				err = fmt.Errorf("instance count is required")
+			}
+			if err == nil {
+				group, name := path.Split(parts[1])
+				fmt.Fprintf(conn, "%v service is scaling to %v\n", parts[1], count)
+				err = m.Scale(strings.TrimSuffix(group, "/"), name, count)
+			}
+		case "wait":
+			var timeout time.Duration
+			if len(parts) > 3 {
+				timeout, err = time.ParseDuration(parts[3])
+			}
+			if err == nil && len(parts) < 3 {
+				err = fmt.Errorf("state is required")
+			}
+			if err == nil {
+				fmt.Fprintf(conn, "waiting %v to be %v\n", parts[1], parts[2])
+				//the client is not sending on waiting, so the peek is returned by closing or next command after result
+				closed := make(chan int)
+				peeking = make(chan int)
+				go func(peeking chan int) {
+					if _, err := reader.Peek(1); err != nil {
+						close(closed)
+					}
+					close(peeking)
+				}(peeking)
+				err = m.wait(parts[1], parts[2], timeout, closed)
+			}
 		case "list":
-			switch parts[1] {
-			case "all":
-				m.Print(conn, "*")
-			default:
-				m.Print(conn, parts[1])
+			format, tmpl := FormatTable, ""
+			if len(parts) > 2 && len(parts[2]) > 0 {
+				format = parts[2]
 			}
-
+			if len(parts) > 3 {
+				tmpl = parts[3]
+			}
+			err = WriteStatus(conn, m.Status(parts[1]), format, tmpl)
+		case "send":
+			if len(parts) > 2 {
+				err = m.Send(parts[1], []byte(parts[2]))
+			} else {
+				err = fmt.Errorf("data is required")
+			}
+			if err == nil {
+				fmt.Fprintf(conn, "send %v bytes to %v success\n", len(parts[2]), parts[1])
+			}
+		case "signal":
+			var sig os.Signal
+			if len(parts) > 2 {
+				sig, err = ParseSignal(parts[2])
+			} else {
+				err = fmt.Errorf("signal is required")
+			}
+			if err == nil {
+				fmt.Fprintf(conn, "%v service is signaling by %v\n", parts[1], parts[2])
+				err = m.Signal(parts[1], sig)
+			}
+		case "reload":
+			fmt.Fprintf(conn, "%v service is reloading\n", parts[1])
+			err = m.ReloadService(parts[1])
+		case "attach":
+			err = m.Attach(parts[1], reader, conn, func(tty bool) {
+				if tty {
+					fmt.Fprintf(conn, "==OK:tty\n")
+				} else {
+					fmt.Fprintf(conn, "==OK:\n")
+				}
+			})
+			if err == nil {
+				//the connection is used by attach, it is closed after detached
+				return
+			}
+		case "history":
+			format, tmpl := FormatTable, ""
+			if len(parts) > 2 && len(parts[2]) > 0 {
+				format = parts[2]
+			}
+			if len(parts) > 3 {
+				tmpl = parts[3]
+			}
+			err = WriteHistory(conn, m.History(parts[1]), format, tmpl)
 		}
 		if err != nil {
 			fmt.Fprintf(conn, "==ERR:%v\n", err)
@@ -162,6 +318,24 @@ func (m *Manager) StopConsole() {
 	m.console = nil
 }
 
+//StopLog will remove the daemon log hook and send all waiting log
+func (m *Manager) StopLog() {
+	if m.logHook == nil {
+		return
+	}
+	hooks := log.LevelHooks{}
+	for level, levelHooks := range log.StandardLogger().Hooks {
+		for _, hook := range levelHooks {
+			if hook != m.logHook {
+				hooks[level] = append(hooks[level], hook)
+			}
+		}
+	}
+	log.StandardLogger().ReplaceHooks(hooks)
+	m.logHook.Close()
+	m.logHook = nil
+}
+
 //StartAll will start all service
 func (m *Manager) StartAll(info io.Writer) (err error) {
 	for _, group := range m.Groups {
@@ -186,8 +360,28 @@ func (m *Manager) StartGroup(info io.Writer, name string) (err error) {
 }
 
 func (m *Manager) startGroup(info io.Writer, group *Group) (err error) {
+	hook := m.groupHook(group)
+	err = hook.run(&group.Hooks, HookPreStart)
+	if err != nil {
+		log.Infof("%v is start fail with %v", group.Name, err)
+		fmt.Fprintf(info, "%v is start fail with %v\n", group.Name, err)
+		return
+	}
+	failed := []string{}
 	for _, service := range group.Services {
 		s := service
+		if len(s.Schedule) > 0 {
+			schedule, scheduleErr := m.StartSchedule(group, &s)
+			if scheduleErr == nil {
+				log.Infof("%v/%v is scheduled, next run at %v", group.Name, s.Name, schedule.Next)
+				fmt.Fprintf(info, "%v/%v is scheduled, next run at %v\n", group.Name, s.Name, schedule.Next)
+			} else {
+				failed = append(failed, s.Name)
+				log.Infof("%v/%v is schedule fail with %v", group.Name, s.Name, scheduleErr)
+				fmt.Fprintf(info, "%v/%v is schedule fail with %v\n", group.Name, s.Name, scheduleErr)
+			}
+			continue
+		}
 		log.Infof("%v/%v is starting", group.Name, s.Name)
 		fmt.Fprintf(info, "%v/%v is starting\n", group.Name, s.Name)
 		err = m.StartService(group, &s)
@@ -195,19 +389,85 @@ func (m *Manager) startGroup(info io.Writer, group *Group) (err error) {
 			log.Infof("%v/%v is started", group.Name, s.Name)
 			fmt.Fprintf(info, "%v/%v is started\n", group.Name, s.Name)
 		} else {
+			failed = append(failed, s.Name)
 			log.Infof("%v/%v is fail with %v", group.Name, s.Name, err)
 			fmt.Fprintf(info, "%v/%v is fail with %v\n", group.Name, s.Name, err)
 		}
+		if err != nil && s.Type == ServiceOneshot {
+			err = fmt.Errorf("oneshot service %v fail with %v", s.Name, err)
+			return
+		}
 	}
+	//the post_start hook is only run when all service is started
+	if len(failed) > 0 {
+		err = fmt.Errorf("service %v start fail", strings.Join(failed, ","))
+		return
+	}
+	err = hook.run(&group.Hooks, HookPostStart)
 	if err != nil {
-		err = fmt.Errorf("some service start fail")
+		log.Infof("%v is start fail with %v", group.Name, err)
+		fmt.Fprintf(info, "%v is start fail with %v\n", group.Name, err)
 	}
 	return
 }
 
-//StartService will start one service
+//groupHook will return hook runner for group hooks, the hook is run with daemon environment
+func (m *Manager) groupHook(group *Group) (hook *hookRunner) {
+	confDir := filepath.Dir(group.Filename)
+	hook = &hookRunner{
+		Prefix: group.Name,
+		Values: map[string]interface{}{
+			"CONF_DIR":      confDir,
+			"CONF_DIR_UNIX": strings.ReplaceAll(confDir, "\\", "/"),
+		},
+		Dir: confDir,
+		Env: os.Environ(),
+	}
+	return
+}
+
+//instanceKey will return the running key of service instance, it is group/name@instance when service instances is configured
+func instanceKey(group *Group, service *Service, instance int) string {
+	if service.Instances > 0 {
+		return fmt.Sprintf("%v/%v@%v", group.Name, service.Name, instance)
+	}
+	return fmt.Sprintf("%v/%v", group.Name, service.Name)
+}
+
+//instanceCount will return the instance count of service, it is the scaled count or instances configure
+func (m *Manager) instanceCount(group *Group, service *Service) (count int) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	count = m.scaledCount(group, service)
+	return
+}
+
+func (m *Manager) scaledCount(group *Group, service *Service) (count int) {
+	count, ok := m.scales[group.Name+"/"+service.Name]
+	if ok {
+		return
+	}
+	count = 1
+	if service.Instances > 0 {
+		count = service.Instances
+	}
+	return
+}
+
+//StartService will start all instance of one service
 func (m *Manager) StartService(group *Group, service *Service) (err error) {
-	key := fmt.Sprintf("%v/%v", group.Name, service.Name)
+	count := m.instanceCount(group, service)
+	for instance := 0; instance < count; instance++ {
+		_, startErr := m.startService(group, service, instance)
+		if err == nil {
+			err = startErr
+		}
+	}
+	return
+}
+
+func (m *Manager) startService(group *Group, service *Service, instance int) (running *Running, err error) {
+	key := instanceKey(group, service, instance)
 	m.locker.Lock()
 	if m.running[key] != nil {
 		err = fmt.Errorf("%v is running", key)
@@ -215,19 +475,46 @@ func (m *Manager) StartService(group *Group, service *Service) (err error) {
 		return
 	}
 	m.locker.Unlock()
+	err = m.startProxy(group)
+	if err != nil {
+		return
+	}
+	m.startWatch(group, service)
 	confDir := filepath.Dir(group.Filename)
 	values := map[string]interface{}{
-		"CONF_DIR":      confDir,
-		"CONF_DIR_UNIX": strings.ReplaceAll(confDir, "\\", "/"),
+		"CONF_DIR":       confDir,
+		"CONF_DIR_UNIX":  strings.ReplaceAll(confDir, "\\", "/"),
+		"INSTANCE":       instance,
+		"INSTANCE_COUNT": m.instanceCount(group, service),
 	}
-	cmdDir := envReplaceEmpty(values, service.Dir, false)
-	if !filepath.IsAbs(cmdDir) {
-		cmdDir = filepath.Join(confDir, cmdDir)
+	ports, err := m.allocPorts(key, service.Ports)
+	if err != nil {
+		return
+	}
+	for name, port := range ports {
+		values[portValue(name)] = port
 	}
+	sandbox := service.Sandbox
+	if sandbox == nil {
+		sandbox = &Sandbox{}
+	}
+	cmdDir := envReplaceEmpty(values, service.Dir, false)
 	cmdPath := envReplaceEmpty(values, service.Path, false)
-	if !filepath.IsAbs(cmdPath) {
-		cmdPath = filepath.Join(confDir, cmdPath)
+	if len(sandbox.Chroot) > 0 {
+		//dir and path is inside chroot
+		cmdDir = filepath.Join("/", cmdDir)
+		if !filepath.IsAbs(cmdPath) {
+			cmdPath = filepath.Join(cmdDir, cmdPath)
+		}
+	} else {
+		if !filepath.IsAbs(cmdDir) {
+			cmdDir = filepath.Join(confDir, cmdDir)
+		}
+		if !filepath.IsAbs(cmdPath) {
+			cmdPath = filepath.Join(confDir, cmdPath)
+		}
 	}
+	outDir := filepath.Join(sandbox.Chroot, cmdDir)
 	cmdArgs := []string{}
 	for _, arg := range service.Args {
 		cmdArgs = append(cmdArgs, envReplaceEmpty(values, arg, false))
@@ -236,75 +523,190 @@ func (m *Manager) StartService(group *Group, service *Service) (err error) {
 	for _, env := range service.Env {
 		cmdEnv = append(cmdEnv, envReplaceEmpty(values, env, false))
 	}
-	var stdoutFile, stderrFile *os.File
-	if len(service.Stdout) > 0 {
-		stdout := envReplaceEmpty(values, service.Stdout, false)
-		if !filepath.IsAbs(stdout) {
-			stdout = filepath.Join(cmdDir, stdout)
-		}
-		stdoutFile, err = os.OpenFile(stdout, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
-		if err != nil {
-			return err
-		}
+	cred, err := lookupCredential(service)
+	if err == nil && cred != nil {
+		err = cred.check()
 	}
-	if len(service.Stderr) > 0 && service.Stderr == service.Stdout {
-		stderrFile = stdoutFile
-	} else if len(service.Stderr) > 0 {
-		stderr := envReplaceEmpty(values, service.Stderr, false)
-		if !filepath.IsAbs(stderr) {
-			stderr = filepath.Join(cmdDir, stderr)
-		}
-		stderrFile, err = os.OpenFile(stderr, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
-		if err != nil {
-			if stdoutFile != nil {
-				stdoutFile.Close()
+	var socketFiles []*os.File
+	if err == nil && len(service.Sockets) > 0 {
+		//the unix socket is relative to working directory
+		addrs := []string{}
+		for _, addr := range service.Sockets {
+			if network, address, _ := parseSocket(addr); network == "unix" && !filepath.IsAbs(address) {
+				addr = "unix://" + filepath.Join(outDir, address)
 			}
-			return err
+			addrs = append(addrs, addr)
 		}
+		socketFiles, err = m.bindSockets(group.Name+"/"+service.Name, addrs)
 	}
-	closeStd := func() {
-		if stdoutFile != nil {
-			stdoutFile.Close()
-		}
-		if stderrFile != nil && stderrFile != stdoutFile {
-			stderrFile.Close()
+	if err != nil {
+		return
+	}
+	//output file is relative to working directory
+	outPath := func(path string) string {
+		path = envReplaceEmpty(values, path, false)
+		if len(path) > 0 && !filepath.IsAbs(path) {
+			path = filepath.Join(outDir, path)
 		}
+		return path
 	}
-	cmd := exec.Cmd{
-		Path:   cmdPath,
-		Args:   append([]string{cmdPath}, cmdArgs...),
+	output, err := openOutput(service, outPath, key, logLine{Group: group.Name, Service: service.Name, Instance: instance}, cred, m.echo(key))
+	if err != nil {
+		return
+	}
+	hook := &hookRunner{
+		Prefix: key,
+		Values: values,
+		Dir:    outDir,
 		Env:    cmdEnv,
-		Dir:    cmdDir,
-		Stdout: stdoutFile,
-		Stderr: stderrFile,
-	}
-	running := &Running{
-		Cmd:     &cmd,
-		Group:   group,
-		Service: service,
-		Waiter:  sync.WaitGroup{},
-	}
-	log.Infof("%v/%v start by \n\tPath:%v\n\tArgs:%v\n\tEnv:%v\n\tDir:%v\n",
-		group.Name, service.Name, cmd.Path, cmd.Args, cmd.Env, cmd.Dir)
-	err = cmd.Start()
+		Cred:   cred,
+	}
+	hook.Out = output.HookOut()
+	err = hook.run(&service.Hooks, HookPreStart)
+	if err != nil {
+		output.Close()
+		return
+	}
+	stdin, stdinCloser, stdinPipe, err := openStdin(service, outPath, func(s string) string { return envReplaceEmpty(values, s, false) })
+	if err != nil {
+		output.Close()
+		return
+	}
+	if stdinCloser != nil {
+		//the stdin is dup to child, it is not used after started
+		defer stdinCloser.Close()
+	}
+	cmd := exec.Cmd{
+		Path:       cmdPath,
+		Args:       append([]string{cmdPath}, cmdArgs...),
+		Env:        cmdEnv,
+		Dir:        cmdDir,
+		Stdin:      stdin,
+		Stdout:     output.Stdout,
+		Stderr:     output.Stderr,
+		ExtraFiles: socketFiles,
+	}
+	if len(socketFiles) > 0 {
+		//the LISTEN_PID is set by exec shim
+		cmd.Env = append(append([]string{}, cmdEnv...), fmt.Sprintf("LISTEN_FDS=%v", len(socketFiles)))
+	}
+	running = &Running{
+		Key:      key,
+		Instance: instance,
+		Cmd:      &cmd,
+		Group:    group,
+		Service:  service,
+		Waiter:   sync.WaitGroup{},
+		hook:     hook,
+		ready:    make(chan int),
+		exited:   make(chan int),
+		stdin:    stdinPipe,
+		output:   output,
+	}
+	log.Infof("%v start by \n\tPath:%v\n\tArgs:%v\n\tEnv:%v\n\tDir:%v\n",
+		key, cmd.Path, cmd.Args, cmd.Env, cmd.Dir)
+	spec := &execSpec{
+		Path:       cmdPath,
+		Credential: cred,
+		Chroot:     sandbox.Chroot,
+		Dir:        cmdDir,
+		ReadOnly:   sandbox.ReadOnly,
+		NoNewPrivs: sandbox.NoNewPrivs,
+		Namespaces: sandbox.Namespaces,
+		ListenPid:  len(socketFiles) > 0,
+	}
+	spec.Umask, err = sandbox.umask()
+	if err != nil {
+		output.Close()
+		running.closeStdin()
+		return
+	}
+	if service.Limits != nil {
+		spec.Rlimits = service.Limits.rlimits()
+		if service.Limits.cgroup() && cgroupSupported() {
+			running.Cgroup, err = createCgroup(key, service.Limits)
+			if err != nil {
+				output.Close()
+				running.closeStdin()
+				return
+			}
+			spec.Cgroup = running.Cgroup
+		} else if service.Limits.cgroup() {
+			log.Warnf("%v cgroup limits is skipped by cgroup v2 is not supported", key)
+		}
+	}
+	err = spec.apply(&cmd)
+	if err == nil && service.TTY {
+		//the stdin is pty slave, the output is read from master which is kept as stdin pipe
+		slave, _ := stdin.(*os.File)
+		applyTTY(&cmd, slave)
+		output.readTTY(stdinPipe)
+	} else if err == nil && m.Foreground {
+		applyProcessGroup(&cmd)
+	}
+	if err == nil {
+		running.Started = time.Now()
+		err = cmd.Start()
+	}
 	if err == nil {
+		output.Started(cmd.Process.Pid)
 		running.State = StateRunning
 		running.Waiter.Add(1)
 		m.locker.Lock()
 		m.running[key] = running
+		m.started[group.Name+"/"+service.Name] = true
+		m.notify()
 		m.locker.Unlock()
 		go func() {
 			running.Err = cmd.Wait()
-			log.Infof("%v/%v is stopped by %v", group.Name, service.Name, running.Err)
+			stopped := time.Now()
+			log.Infof("%v is stopped by %v", key, running.Err)
+			m.locker.Lock()
 			running.State = StateStopped
-			closeStd()
+			m.locker.Unlock()
+			//the piped output is copied before hook output is written
+			output.Wait(OutputDrainTimeout)
+			if service.Type == ServiceOneshot && running.Err == nil {
+				//oneshot is started when it exit with success
+				running.Err = hook.run(&service.Hooks, HookPostStart)
+			}
+			if err := hook.run(&service.Hooks, HookPostStop); err != nil {
+				log.Warnf("%v %v", key, err)
+			}
+			output.Close()
+			running.closeStdin()
+			if len(running.Cgroup) > 0 {
+				killCgroup(running.Cgroup)
+				removeCgroup(running.Cgroup)
+			}
 			m.locker.Lock()
 			delete(m.running, key)
+			m.exits[key] = running
+			exit := newExit(running, stopped, output.StderrLines())
+			m.addHistory(exit)
+			m.notify()
 			m.locker.Unlock()
+			//oneshot and schedule service is expected to exit by itself with success
+			if !exit.Requested && (exit.Crashed() || (service.Type != ServiceOneshot && len(service.Schedule) < 1)) {
+				m.emit(newEvent(EventExit, running, fmt.Sprintf("%v is exited unexpectedly with %v", key, running.Err), exit))
+			}
+			m.autoRestart(running, exit)
+			close(running.exited)
 			running.Waiter.Done()
 		}()
+		go m.runProbe(running)
+		if service.Type == ServiceOneshot {
+			running.Waiter.Wait()
+			err = running.Err
+		} else {
+			err = hook.run(&service.Hooks, HookPostStart)
+		}
 	} else {
-		closeStd()
+		output.Close()
+		running.closeStdin()
+		if len(running.Cgroup) > 0 {
+			removeCgroup(running.Cgroup)
+		}
 	}
 	return
 }
@@ -318,62 +720,192 @@ func (m *Manager) StopAll() (err error) {
 //StopGroup will stop all service in group
 func (m *Manager) StopGroup(group string) (err error) {
 	stopping := []*Running{}
+	unscheduling := []*Schedule{}
+	groups := map[string]*Group{}
 	m.locker.Lock()
-	for _, running := range m.running {
+	for _, schedule := range m.schedules {
+		if group == "*" || schedule.Group.Name == group {
+			unscheduling = append(unscheduling, schedule)
+			groups[schedule.Group.Name] = schedule.Group
+		}
+	}
+	for key, running := range m.running {
+		if _, ok := m.schedules[key]; ok {
+			continue
+		}
 		if group == "*" || running.Group.Name == group {
 			stopping = append(stopping, running)
+			groups[running.Group.Name] = running.Group
 		}
 	}
 	m.locker.Unlock()
+	m.cancelRestart(func(key string) bool { return group == "*" || strings.HasPrefix(key, group+"/") })
+	m.stopWatch(watchMatch(group))
+	for _, g := range groups {
+		if hookErr := m.groupHook(g).run(&g.Hooks, HookPreStop); hookErr != nil {
+			log.Warnf("%v %v", g.Name, hookErr)
+		}
+	}
+	for _, schedule := range unscheduling {
+		log.Infof("%v/%v is unscheduling", schedule.Group.Name, schedule.Service.Name)
+		m.StopSchedule(schedule.Group.Name, schedule.Service.Name)
+	}
+	//all service is stopping at same time to not wait StopTimeout on each
+	stopped := sync.WaitGroup{}
 	for _, running := range stopping {
-		log.Infof("%v/%v is stopping", running.Group.Name, running.Service.Name)
-		err = m.StopService(running.Group.Name, running.Service.Name)
-		log.Infof("%v/%v is stopped", running.Group.Name, running.Service.Name)
+		stopped.Add(1)
+		go func(running *Running) {
+			defer stopped.Done()
+			log.Infof("%v is stopping", running.Key)
+			m.stopRunning(running)
+			log.Infof("%v is stopped", running.Key)
+		}(running)
+	}
+	stopped.Wait()
+	m.unmarkStarted(func(key string) bool { return group == "*" || strings.HasPrefix(key, group+"/") })
+	for _, g := range groups {
+		if hookErr := m.groupHook(g).run(&g.Hooks, HookPostStop); hookErr != nil {
+			log.Warnf("%v %v", g.Name, hookErr)
+		}
 	}
 	return
 }
 
-//StopService will stop single service in group
+//StopService will stop single service in group, all instance will be stopped if name is not having instance
 func (m *Manager) StopService(group, name string) (err error) {
 	key := fmt.Sprintf("%v/%v", group, name)
+	stopping := []*Running{}
 	m.locker.Lock()
-	running := m.running[key]
-	if running == nil {
+	for k, running := range m.running {
+		if k == key || strings.HasPrefix(k, key+"@") {
+			stopping = append(stopping, running)
+		}
+	}
+	m.locker.Unlock()
+	canceled := m.cancelRestart(func(k string) bool { return k == key || strings.HasPrefix(k, key+"@") })
+	m.stopWatch(func(k string) bool { return k == key })
+	if len(stopping) < 1 && canceled < 1 {
 		err = fmt.Errorf("%v is not running", key)
-		m.locker.Unlock()
 		return
 	}
+	for _, running := range stopping {
+		m.stopRunning(running)
+	}
+	m.unmarkStarted(func(k string) bool { return k == key })
+	return
+}
+
+//unmarkStarted will clear the started mark of group/service which is matched and not having running instance,
+//the service is started by scaling only when it is marked
+func (m *Manager) unmarkStarted(match func(key string) bool) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for key := range m.started {
+		if !match(key) {
+			continue
+		}
+		running := false
+		for k := range m.running {
+			running = running || k == key || strings.HasPrefix(k, key+"@")
+		}
+		if !running {
+			delete(m.started, key)
+		}
+	}
+}
+
+//notify will wake up all waiter on state changed, it must be called with locker
+func (m *Manager) notify() {
+	close(m.changed)
+	m.changed = make(chan int)
+}
+
+func (m *Manager) stopRunning(running *Running) {
+	m.locker.Lock()
+	running.Requested = true
+	stopSignal := m.StopSignal
 	m.locker.Unlock()
+	if err := running.hook.run(&running.Service.Hooks, HookPreStop); err != nil {
+		log.Warnf("%v %v", running.Key, err)
+	}
+	if stopSignal != nil && running.Cmd.Process.Signal(stopSignal) == nil {
+		timer := time.NewTimer(StopTimeout)
+		select {
+		case <-running.exited:
+		case <-timer.C:
+			log.Warnf("%v is not exited in %v by %v, it will be killed", running.Key, StopTimeout, stopSignal)
+		}
+		timer.Stop()
+	}
+	if len(running.Cgroup) > 0 {
+		killCgroup(running.Cgroup)
+	}
 	running.Cmd.Process.Kill()
 	running.Waiter.Wait()
-	return
 }
 
-//Print will show running
-func (m *Manager) Print(info io.Writer, group string) {
+//Scale will change the instance count of service, the extra instance will be stopped and the missing instance will be started if service is running
+func (m *Manager) Scale(group, name string, count int) (err error) {
+	g := m.Find(group)
+	if g == nil {
+		err = fmt.Errorf("group %v is not exist", group)
+		return
+	}
+	var service *Service
+	for i := range g.Services {
+		if g.Services[i].Name == name {
+			service = &g.Services[i]
+			break
+		}
+	}
+	if service == nil {
+		err = fmt.Errorf("service %v/%v is not exist", group, name)
+		return
+	}
+	if service.Instances < 1 {
+		err = fmt.Errorf("service %v/%v is not configured instances", group, name)
+		return
+	}
+	if count < 0 {
+		err = fmt.Errorf("instance count %v is invalid", count)
+		return
+	}
+	stopping := []*Running{}
+	starting := []int{}
 	m.locker.Lock()
-	defer m.locker.Unlock()
-	fmt.Fprintf(info, "%v\t\t%v\t\t%v\t\t%v\t\t%v\t\t%v\n", "STATE", "NAME", "GROUP", "PATH", "ARGS", "DIR")
+	m.scales[group+"/"+name] = count
 	for _, running := range m.running {
-		if group != "*" && running.Group.Name != group {
-			continue
+		if running.Group.Name == group && running.Service.Name == name && running.Instance >= count {
+			stopping = append(stopping, running)
 		}
-		fmt.Fprintf(info, "%v\t\t%v\t\t%v\t\t%v\t\t%v\t\t%v\n", "running", running.Service.Name, running.Group.Name, running.Service.Path, running.Cmd.Args, running.Cmd.Dir)
 	}
-	for _, g := range m.Groups {
-		if group != "*" && g.Name != group {
-			continue
+	//the missing instance is started only when service is started and not stopped by user, it is kept on scaling to 0
+	started := m.started[group+"/"+name]
+	for instance := 0; started && instance < count; instance++ {
+		if m.running[instanceKey(g, service, instance)] == nil {
+			starting = append(starting, instance)
 		}
-		for _, service := range g.Services {
-			if _, ok := m.running[g.Name+"/"+service.Name]; ok {
-				continue
-			}
-			dir := filepath.Dir(g.Filename)
-			cmdDir := service.Dir
-			if !filepath.IsAbs(cmdDir) {
-				cmdDir = filepath.Join(dir, cmdDir)
-			}
-			fmt.Fprintf(info, "%v\t\t%v\t\t%v\t\t%v\t\t%v\t\t%v\n", "stopped", service.Name, g.Name, service.Path, service.Args, cmdDir)
+	}
+	m.locker.Unlock()
+	log.Infof("%v/%v is scaling to %v, stopping %v, starting %v", group, name, count, len(stopping), len(starting))
+	for _, running := range stopping {
+		m.stopRunning(running)
+	}
+	prefix := group + "/" + name + "@"
+	m.releasePorts(func(key string) bool {
+		instance, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+		return strings.HasPrefix(key, prefix) && err == nil && instance >= count
+	})
+	for _, instance := range starting {
+		_, startErr := m.startService(g, service, instance)
+		if err == nil {
+			err = startErr
 		}
 	}
+	return
+}
+
+//Print will show running, pattern is all, group name or group/service with glob like web/* or */worker-*
+func (m *Manager) Print(info io.Writer, pattern string) {
+	WriteStatus(info, m.Status(pattern), FormatWide, "")
 }
@@ -2,12 +2,20 @@ package serviced
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
 
+func TestMain(m *testing.M) {
+	RunShim()
+	os.Exit(m.Run())
+}
+
 func TestManager(t *testing.T) {
 	var err error
 	m := NewManager()
@@ -59,3 +67,99 @@ func TestManager(t *testing.T) {
 		return
 	}
 }
+
+func TestInstances(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["instances"] = Group{
+		Name:     "instances",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:      "worker",
+				Path:      "/bin/sh",
+				Args:      []string{"-c", "echo ${INSTANCE}/${INSTANCE_COUNT} && sleep 10"},
+				Stdout:    "worker_${INSTANCE}.log",
+				Instances: 3,
+			},
+			{
+				Name: "single",
+				Path: "/bin/sleep",
+				Args: []string{"10"},
+			},
+		},
+	}
+	defer func() {
+		for i := 0; i < 3; i++ {
+			os.Remove(fmt.Sprintf("worker_%v.log", i))
+		}
+	}()
+	err := m.StartGroup(ioutil.Discard, "instances")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(m.running) != 4 || m.running["instances/worker@2"] == nil || m.running["instances/single"] == nil {
+		t.Errorf("%v", m.running)
+		return
+	}
+	//wait the output before instance is stopped by scale
+	for i := 0; i < 100; i++ {
+		if data, _ := ioutil.ReadFile("worker_2.log"); len(data) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err = m.Scale("instances", "worker", 1)
+	if err != nil || len(m.running) != 2 || m.running["instances/worker@0"] == nil {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Scale("instances", "worker", 2)
+	if err != nil || len(m.running) != 3 || m.running["instances/worker@1"] == nil {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Scale("instances", "worker", 0)
+	if err != nil || len(m.running) != 1 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Scale("instances", "worker", 3)
+	if err != nil || len(m.running) != 4 || m.running["instances/worker@2"] == nil {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	//wait the output of scaled up instance before it is stopped
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, _ := ioutil.ReadFile("worker_2.log"); strings.TrimSpace(string(data)) == "2/3\n2/3" {
+			break
+		}
+	}
+	if m.Scale("instances", "single", 2) == nil || m.Scale("instances", "none", 2) == nil || m.Scale("none", "worker", 2) == nil || m.Scale("instances", "worker", -1) == nil {
+		t.Error("error")
+		return
+	}
+	m.Print(os.Stdout, "instances")
+	err = m.StopService("instances", "worker")
+	if err != nil || len(m.running) != 1 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	//the stopped service is not started by scaling
+	err = m.Scale("instances", "worker", 2)
+	if err != nil || len(m.running) != 1 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	m.StopGroup("instances")
+	data, _ := ioutil.ReadFile("worker_2.log")
+	//instance 2 is started again by scaling from 0
+	if strings.TrimSpace(string(data)) != "2/3\n2/3" {
+		t.Errorf("%v", string(data))
+		return
+	}
+}
@@ -0,0 +1,276 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//matched is the service instance matched by pattern
+type matched struct {
+	Group    *Group
+	Service  *Service
+	Instance int
+	Key      string
+}
+
+//matchKey will return true if running key like group/service@instance is matched by pattern,
+//all instance is matched when pattern is not having instance.
+func matchKey(pattern, key string) bool {
+	if ok, _ := path.Match(pattern, key); ok {
+		return true
+	}
+	if idx := strings.LastIndex(key, "@"); idx > 0 && !strings.Contains(pattern, "@") {
+		ok, _ := path.Match(pattern, key[:idx])
+		return ok
+	}
+	return false
+}
+
+//matchTarget will return true if group or running key is matched by pattern,
+//pattern is all/* for all, group name pattern for group, group/service pattern for service
+func matchTarget(pattern, group, key string) bool {
+	if pattern == "all" || pattern == "*" {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, group)
+		return ok
+	}
+	return matchKey(pattern, key)
+}
+
+//matchGroups will return all group matched by name pattern, sorted by name
+func (m *Manager) matchGroups(pattern string) (groups []*Group) {
+	for _, group := range m.Groups {
+		g := group
+		if matchTarget(pattern, g.Name, "") {
+			groups = append(groups, &g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return
+}
+
+//matchServices will return all service instance matched by group/service pattern
+func (m *Manager) matchServices(pattern string) (services []*matched) {
+	for _, group := range m.matchGroups("*") {
+		for i := range group.Services {
+			service := &group.Services[i]
+			count := m.instanceCount(group, service)
+			for instance := 0; instance < count; instance++ {
+				key := instanceKey(group, service, instance)
+				if matchKey(pattern, key) {
+					services = append(services, &matched{Group: group, Service: service, Instance: instance, Key: key})
+				}
+			}
+		}
+	}
+	return
+}
+
+//Start will start group or service by pattern, pattern is all, group name or group/service with glob like web/* or */worker-*
+func (m *Manager) Start(info io.Writer, pattern string) (err error) {
+	if !strings.Contains(pattern, "/") {
+		groups := m.matchGroups(pattern)
+		if len(groups) < 1 {
+			err = fmt.Errorf("group %v is not exist", pattern)
+			return
+		}
+		for _, group := range groups {
+			startErr := m.startGroup(info, group)
+			if err == nil {
+				err = startErr
+			}
+		}
+		return
+	}
+	services := m.matchServices(pattern)
+	if len(services) < 1 {
+		err = fmt.Errorf("service %v is not exist", pattern)
+		return
+	}
+	for _, service := range services {
+		startErr := m.startMatched(info, service)
+		if err == nil {
+			err = startErr
+		}
+	}
+	return
+}
+
+func (m *Manager) startMatched(info io.Writer, service *matched) (err error) {
+	if len(service.Service.Schedule) > 0 {
+		var schedule *Schedule
+		schedule, err = m.StartSchedule(service.Group, service.Service)
+		if err == nil {
+			log.Infof("%v is scheduled, next run at %v", service.Key, schedule.Next)
+			fmt.Fprintf(info, "%v is scheduled, next run at %v\n", service.Key, schedule.Next)
+		} else {
+			log.Infof("%v is schedule fail with %v", service.Key, err)
+			fmt.Fprintf(info, "%v is schedule fail with %v\n", service.Key, err)
+		}
+		return
+	}
+	log.Infof("%v is starting", service.Key)
+	fmt.Fprintf(info, "%v is starting\n", service.Key)
+	_, err = m.startService(service.Group, service.Service, service.Instance)
+	if err == nil {
+		log.Infof("%v is started", service.Key)
+		fmt.Fprintf(info, "%v is started\n", service.Key)
+	} else {
+		log.Infof("%v is fail with %v", service.Key, err)
+		fmt.Fprintf(info, "%v is fail with %v\n", service.Key, err)
+	}
+	return
+}
+
+//Stop will stop group or service by pattern, pattern is all, group name or group/service with glob like web/* or */worker-*
+func (m *Manager) Stop(pattern string) (err error) {
+	if !strings.Contains(pattern, "/") {
+		if pattern == "all" {
+			pattern = "*"
+		}
+		if pattern == "*" || !strings.ContainsAny(pattern, "*?[") {
+			err = m.StopGroup(pattern)
+			return
+		}
+		for _, group := range m.matchGroups(pattern) {
+			m.StopGroup(group.Name)
+		}
+		return
+	}
+	stopping := []*Running{}
+	unscheduling := []*Schedule{}
+	m.locker.Lock()
+	for key, schedule := range m.schedules {
+		if matchKey(pattern, key) {
+			unscheduling = append(unscheduling, schedule)
+		}
+	}
+	for key, running := range m.running {
+		if _, ok := m.schedules[key]; !ok && matchKey(pattern, key) {
+			stopping = append(stopping, running)
+		}
+	}
+	m.locker.Unlock()
+	canceled := m.cancelRestart(func(key string) bool { return matchKey(pattern, key) })
+	m.stopWatch(watchMatch(pattern))
+	if len(stopping) < 1 && len(unscheduling) < 1 && canceled < 1 {
+		err = fmt.Errorf("service %v is not running", pattern)
+		return
+	}
+	for _, schedule := range unscheduling {
+		log.Infof("%v/%v is unscheduling", schedule.Group.Name, schedule.Service.Name)
+		m.StopSchedule(schedule.Group.Name, schedule.Service.Name)
+	}
+	for _, running := range stopping {
+		log.Infof("%v is stopping", running.Key)
+		m.stopRunning(running)
+		log.Infof("%v is stopped", running.Key)
+	}
+	m.unmarkStarted(func(key string) bool { return matchKey(pattern, key) })
+	return
+}
+
+//Restart will restart group or service by pattern, pattern is all, group name or group/service with glob like web/* or */worker-*
+func (m *Manager) Restart(info io.Writer, pattern string) (err error) {
+	if !strings.Contains(pattern, "/") {
+		groups := m.matchGroups(pattern)
+		if len(groups) < 1 {
+			err = fmt.Errorf("group %v is not exist", pattern)
+			return
+		}
+		for _, group := range groups {
+			fmt.Fprintf(info, "%v is restarting\n", group.Name)
+			m.StopGroup(group.Name)
+			startErr := m.startGroup(info, group)
+			if err == nil {
+				err = startErr
+			}
+		}
+		return
+	}
+	services := m.matchServices(pattern)
+	if len(services) < 1 {
+		err = fmt.Errorf("service %v is not exist", pattern)
+		return
+	}
+	for _, service := range services {
+		fmt.Fprintf(info, "%v is restarting\n", service.Key)
+		//the waiting restart is replaced by this restart
+		key := service.Key
+		m.cancelRestart(func(k string) bool { return k == key })
+		if len(service.Service.Schedule) > 0 {
+			m.StopSchedule(service.Group.Name, service.Service.Name)
+		} else {
+			m.locker.RLock()
+			running := m.running[service.Key]
+			m.locker.RUnlock()
+			if running != nil {
+				m.stopRunning(running)
+			}
+		}
+		startErr := m.startMatched(info, service)
+		if err == nil {
+			err = startErr
+		}
+	}
+	return
+}
+
+//RollingRestart will restart service matched by pattern one by one, it will wait each service to be ready before restarting next,
+//the rolling is aborted when any service is not ready.
+func (m *Manager) RollingRestart(info io.Writer, pattern string) (err error) {
+	if !strings.Contains(pattern, "/") {
+		if len(m.matchGroups(pattern)) < 1 {
+			err = fmt.Errorf("group %v is not exist", pattern)
+			return
+		}
+		pattern += "/*"
+	}
+	services := m.matchServices(pattern)
+	if len(services) < 1 {
+		err = fmt.Errorf("service %v is not exist", pattern)
+		return
+	}
+	restarted := []string{}
+	for i, service := range services {
+		if len(service.Service.Schedule) > 0 || service.Service.Type == ServiceOneshot {
+			fmt.Fprintf(info, "%v is skipped by not long-running service\n", service.Key)
+			continue
+		}
+		fmt.Fprintf(info, "%v is restarting\n", service.Key)
+		m.locker.RLock()
+		running := m.running[service.Key]
+		m.locker.RUnlock()
+		if running != nil {
+			m.stopRunning(running)
+		}
+		running, err = m.startService(service.Group, service.Service, service.Instance)
+		if err == nil {
+			fmt.Fprintf(info, "%v is waiting ready\n", service.Key)
+			err = m.waitReady(running)
+		}
+		if err != nil {
+			pending := []string{}
+			for _, s := range services[i+1:] {
+				pending = append(pending, s.Key)
+			}
+			log.Warnf("rolling restart %v is aborted on %v with %v", pattern, service.Key, err)
+			fmt.Fprintf(info, "rolling restart is aborted on %v with %v\n", service.Key, err)
+			fmt.Fprintf(info, "restarted: %v\n", strings.Join(restarted, ","))
+			fmt.Fprintf(info, "not restarted: %v\n", strings.Join(pending, ","))
+			err = fmt.Errorf("rolling restart is aborted on %v with %v", service.Key, err)
+			return
+		}
+		fmt.Fprintf(info, "%v is ready\n", service.Key)
+		restarted = append(restarted, service.Key)
+	}
+	fmt.Fprintf(info, "rolling restart is done with %v service\n", len(restarted))
+	return
+}
@@ -0,0 +1,203 @@
+package serviced
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchKey(t *testing.T) {
+	for _, c := range []struct {
+		Pattern string
+		Group   string
+		Key     string
+		Matched bool
+	}{
+		{"all", "web", "web/api", true},
+		{"*", "web", "web/api", true},
+		{"web", "web", "web/api", true},
+		{"w*", "web", "web/api", true},
+		{"db", "web", "web/api", false},
+		{"web/api", "web", "web/api", true},
+		{"web/*", "web", "web/api", true},
+		{"*/api", "web", "web/api", true},
+		{"*/worker-*", "job", "job/worker-1@2", true},
+		{"job/worker-1@2", "job", "job/worker-1@2", true},
+		{"job/worker-1@1", "job", "job/worker-1@2", false},
+		{"web/*", "job", "job/api", false},
+		{"web/ap", "web", "web/api", false},
+	} {
+		if matchTarget(c.Pattern, c.Group, c.Key) != c.Matched {
+			t.Errorf("%v,%v", c.Pattern, c.Key)
+			return
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	for _, name := range []string{"web", "job"} {
+		m.Groups[name] = Group{
+			Name:     name,
+			Filename: "test-service.json",
+			Services: []Service{
+				{Name: "api", Path: "/bin/sleep", Args: []string{"10"}},
+				{Name: "worker-1", Path: "/bin/sleep", Args: []string{"10"}, Instances: 2},
+			},
+		}
+	}
+	err := m.Start(ioutil.Discard, "web/api")
+	if err != nil || len(m.running) != 1 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Start(ioutil.Discard, "*/worker-*")
+	if err != nil || len(m.running) != 5 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	old := m.running["job/worker-1@1"]
+	err = m.Restart(ioutil.Discard, "job/worker-1@1")
+	if err != nil || len(m.running) != 5 || m.running["job/worker-1@1"] == old || m.running["job/worker-1@1"] == nil {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Stop("web/*")
+	if err != nil || len(m.running) != 2 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.Restart(ioutil.Discard, "job")
+	if err != nil || len(m.running) != 3 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	if m.Start(ioutil.Discard, "none/*") == nil || m.Start(ioutil.Discard, "none") == nil || m.Stop("web/*") == nil || m.Restart(ioutil.Discard, "x/y") == nil {
+		t.Error("error")
+		return
+	}
+	m.Stop("all")
+	if len(m.running) != 0 {
+		t.Errorf("%v", m.running)
+		return
+	}
+}
+
+func TestRollingRestartDelay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["rolling"] = Group{
+		Name:     "rolling",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "delay", Path: "/bin/sleep", Args: []string{"10"}, Ready: &Probe{Command: "true", Delay: "300ms", Timeout: "100ms"}},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "rolling")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	//the ready is waited by delay+timeout
+	err = m.RollingRestart(ioutil.Discard, "rolling/delay")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestStopWaitingRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-restart.log")
+	m := NewManager()
+	m.init()
+	m.Groups["restart"] = Group{
+		Name:     "restart",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "fail", Path: "/bin/sh", Args: []string{"-c", "echo start >> test-restart.log; exit 1"}, Restart: &RestartPolicy{Policy: RestartOnFailure, Delay: "500ms"}},
+		},
+	}
+	err := m.Start(ioutil.Discard, "restart/fail")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	//stop while waiting restart delay
+	err = m.Stop("restart/fail")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(600 * time.Millisecond)
+	data, _ := ioutil.ReadFile("test-restart.log")
+	if strings.Count(string(data), "start") != 1 || len(m.running) != 0 {
+		t.Errorf("%v,%v", string(data), m.running)
+		return
+	}
+	if m.Stop("restart/fail") == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+	m := NewManager()
+	m.init()
+	m.Groups["rolling"] = Group{
+		Name:     "rolling",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "tcp", Path: "/bin/sleep", Args: []string{"10"}, Ready: &Probe{TCP: listener.Addr().String(), Delay: "10ms"}},
+			{Name: "cmd", Path: "/bin/sleep", Args: []string{"10"}, Ready: &Probe{Command: "test ${INSTANCE} = 0", Interval: "10ms"}, Instances: 2},
+			{Name: "none", Path: "/bin/sleep", Args: []string{"10"}},
+		},
+	}
+	err = m.Start(ioutil.Discard, "rolling")
+	if err != nil || len(m.running) != 4 {
+		t.Errorf("%v,%v", err, m.running)
+		return
+	}
+	err = m.RollingRestart(ioutil.Discard, "rolling/tcp")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	m.Groups["rolling"].Services[1].Ready.Timeout = "100ms"
+	info := bytes.NewBuffer(nil)
+	err = m.RollingRestart(info, "rolling")
+	if err == nil || !strings.Contains(info.String(), "not restarted: rolling/none") {
+		t.Errorf("%v,%v", err, info.String())
+		return
+	}
+	if m.RollingRestart(ioutil.Discard, "none") == nil || m.RollingRestart(ioutil.Discard, "none/x") == nil {
+		t.Error("error")
+		return
+	}
+	m.StopAll()
+}
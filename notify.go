@@ -0,0 +1,320 @@
+package serviced
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//EventExit is the event of service exited unexpectedly
+	EventExit = "exit"
+	//EventRestartLimit is the event of service restart limit is reached
+	EventRestartLimit = "restart_limit"
+	//EventUnhealthy is the event of service health check is fail
+	EventUnhealthy = "unhealthy"
+)
+
+const (
+	//SinkWebhook is the sink to post event as json to url
+	SinkWebhook = "webhook"
+	//SinkExec is the sink to run command with event
+	SinkExec = "exec"
+	//SinkSMTP is the sink to send event by email
+	SinkSMTP = "smtp"
+)
+
+//Event is the manager event sent to notify sink
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Key        string    `json:"key"`
+	Group      string    `json:"group"`
+	Service    string    `json:"service"`
+	Message    string    `json:"message"`
+	Exit       *Exit     `json:"exit,omitempty"`
+	Suppressed int       `json:"suppressed,omitempty"`
+}
+
+func newEvent(eventType string, running *Running, message string, exit *Exit) (event *Event) {
+	event = &Event{
+		Type:    eventType,
+		Time:    time.Now(),
+		Key:     running.Key,
+		Group:   running.Group.Name,
+		Service: running.Service.Name,
+		Message: message,
+		Exit:    exit,
+	}
+	return
+}
+
+//Notify is the notify configure
+type Notify struct {
+	Sinks []*NotifySink `json:"sinks"`
+}
+
+//check will check all sink configure
+func (n *Notify) check() (err error) {
+	for index, sink := range n.Sinks {
+		err = sink.check()
+		if err != nil {
+			err = fmt.Errorf("notify %v sink %v", index, err)
+			return
+		}
+	}
+	return
+}
+
+//NotifySink is the notify sink configure, events/groups is the filter which is all when empty,
+//rate_limit is the min interval of sending same event on same service, the suppressed count is sent on next event.
+type NotifySink struct {
+	Type      string   `json:"type"`
+	Events    []string `json:"events,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	RateLimit string   `json:"rate_limit,omitempty"`
+	Retry     int      `json:"retry,omitempty"`
+	Backoff   string   `json:"backoff,omitempty"`
+	Timeout   string   `json:"timeout,omitempty"`
+	//URL and Headers is used by webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	//Command is used by exec, it run by shell with SERVICED_EVENT_* env and json event on stdin
+	Command string `json:"command,omitempty"`
+	//Addr/Username/Password/From/To is used by smtp
+	Addr     string   `json:"addr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+
+	sent       map[string]time.Time
+	suppressed map[string]int
+	locker     sync.Mutex
+}
+
+//durations will return rate limit/backoff/timeout with default 0/1s/10s
+func (n *NotifySink) durations() (rate, backoff, timeout time.Duration, err error) {
+	backoff, timeout = time.Second, 10*time.Second
+	for _, d := range []struct {
+		name  string
+		value string
+		out   *time.Duration
+	}{{"rate_limit", n.RateLimit, &rate}, {"backoff", n.Backoff, &backoff}, {"timeout", n.Timeout, &timeout}} {
+		if len(d.value) < 1 {
+			continue
+		}
+		*d.out, err = time.ParseDuration(d.value)
+		if err != nil {
+			err = fmt.Errorf("parse %v %v fail with %v", d.name, d.value, err)
+			return
+		}
+	}
+	return
+}
+
+//check will check sink configure
+func (n *NotifySink) check() (err error) {
+	switch n.Type {
+	case SinkWebhook:
+		if len(n.URL) < 1 {
+			err = fmt.Errorf("webhook url is required")
+		}
+	case SinkExec:
+		if len(n.Command) < 1 {
+			err = fmt.Errorf("exec command is required")
+		}
+	case SinkSMTP:
+		if len(n.Addr) < 1 || len(n.From) < 1 || len(n.To) < 1 {
+			err = fmt.Errorf("smtp addr/from/to is required")
+		}
+	default:
+		err = fmt.Errorf("type must be %v, %v or %v", SinkWebhook, SinkExec, SinkSMTP)
+	}
+	for _, event := range n.Events {
+		if err == nil && event != EventExit && event != EventRestartLimit && event != EventUnhealthy {
+			err = fmt.Errorf("event must be %v, %v or %v", EventExit, EventRestartLimit, EventUnhealthy)
+		}
+	}
+	if err == nil && n.Retry < 0 {
+		err = fmt.Errorf("retry must be positive")
+	}
+	if err == nil {
+		_, _, _, err = n.durations()
+	}
+	return
+}
+
+//match will return true if event is subscribed by events/groups filter
+func (n *NotifySink) match(event *Event) bool {
+	matched := len(n.Events) < 1
+	for _, e := range n.Events {
+		matched = matched || e == event.Type
+	}
+	if !matched {
+		return false
+	}
+	matched = len(n.Groups) < 1
+	for _, pattern := range n.Groups {
+		ok, _ := path.Match(pattern, event.Group)
+		matched = matched || ok
+	}
+	return matched
+}
+
+//allow will return true if event is not limited by rate, the suppressed count is set to event when allowed
+func (n *NotifySink) allow(event *Event) bool {
+	rate, _, _, _ := n.durations()
+	if rate <= 0 {
+		return true
+	}
+	n.locker.Lock()
+	defer n.locker.Unlock()
+	if n.sent == nil {
+		n.sent = map[string]time.Time{}
+		n.suppressed = map[string]int{}
+	}
+	key := event.Type + " " + event.Key
+	if last, ok := n.sent[key]; ok && event.Time.Sub(last) < rate {
+		n.suppressed[key]++
+		return false
+	}
+	n.sent[key] = event.Time
+	event.Suppressed = n.suppressed[key]
+	delete(n.suppressed, key)
+	return true
+}
+
+//send will send event to sink and retry with doubled backoff on fail
+func (n *NotifySink) send(event *Event) (err error) {
+	_, backoff, _, _ := n.durations()
+	for i := 0; ; i++ {
+		switch n.Type {
+		case SinkWebhook:
+			err = n.sendWebhook(event)
+		case SinkExec:
+			err = n.sendExec(event)
+		case SinkSMTP:
+			err = n.sendSMTP(event)
+		}
+		if err == nil || i >= n.Retry {
+			break
+		}
+		log.Warnf("notify %v %v event to %v fail with %v, will retry after %v", event.Key, event.Type, n.Type, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return
+}
+
+func (n *NotifySink) sendWebhook(event *Event) (err error) {
+	_, _, timeout, _ := n.durations()
+	req, err := http.NewRequest("POST", n.URL, bytes.NewBufferString(toJSON(event)))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = fmt.Errorf("webhook response status %v", res.Status)
+	}
+	return
+}
+
+func (n *NotifySink) sendExec(event *Event) (err error) {
+	_, _, timeout, _ := n.durations()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", n.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", n.Command)
+	}
+	cmd.Env = append(os.Environ(),
+		"SERVICED_EVENT_TYPE="+event.Type,
+		"SERVICED_EVENT_KEY="+event.Key,
+		"SERVICED_EVENT_GROUP="+event.Group,
+		"SERVICED_EVENT_SERVICE="+event.Service,
+		"SERVICED_EVENT_MESSAGE="+event.Message,
+	)
+	cmd.Stdin = bytes.NewBufferString(toJSON(event))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("%v, output is %v", err, strings.TrimSpace(string(output)))
+	}
+	return
+}
+
+func (n *NotifySink) sendSMTP(event *Event) (err error) {
+	var auth smtp.Auth
+	if len(n.Username) > 0 {
+		host := n.Addr
+		if idx := strings.LastIndex(host, ":"); idx > 0 {
+			host = host[:idx]
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	body := bytes.NewBuffer(nil)
+	fmt.Fprintf(body, "From: %v\r\n", n.From)
+	fmt.Fprintf(body, "To: %v\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(body, "Subject: [serviced] %v %v\r\n", event.Type, event.Key)
+	fmt.Fprintf(body, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(body, "%v\r\n\r\n", event.Message)
+	fmt.Fprintf(body, "Time: %v\r\n", event.Time.Format(time.RFC3339))
+	if event.Suppressed > 0 {
+		fmt.Fprintf(body, "Suppressed: %v\r\n", event.Suppressed)
+	}
+	if event.Exit != nil {
+		fmt.Fprintf(body, "Exit Code: %v\r\n", event.Exit.Code)
+		if len(event.Exit.Signal) > 0 {
+			fmt.Fprintf(body, "Signal: %v\r\n", event.Exit.Signal)
+		}
+		if len(event.Exit.Stderr) > 0 {
+			fmt.Fprintf(body, "Stderr:\r\n%v\r\n", strings.Join(event.Exit.Stderr, "\r\n"))
+		}
+	}
+	err = smtp.SendMail(n.Addr, auth, n.From, n.To, body.Bytes())
+	return
+}
+
+//emit will send event to all matched sink in background
+func (m *Manager) emit(event *Event) {
+	m.locker.RLock()
+	notify := m.Notify
+	m.locker.RUnlock()
+	if notify == nil {
+		return
+	}
+	for _, sink := range notify.Sinks {
+		sinkEvent := *event
+		if !sink.match(&sinkEvent) || !sink.allow(&sinkEvent) {
+			continue
+		}
+		go func(sink *NotifySink, event *Event) {
+			err := sink.send(event)
+			if err != nil {
+				log.Warnf("notify %v %v event to %v fail with %v", event.Key, event.Type, sink.Type, err)
+			}
+		}(sink, &sinkEvent)
+	}
+}
@@ -0,0 +1,182 @@
+package serviced
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	events := make(chan *Event, 100)
+	failed := 0
+	locker := sync.Mutex{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locker.Lock()
+		defer locker.Unlock()
+		if failed < 1 {
+			failed++
+			w.WriteHeader(500)
+			return
+		}
+		event := &Event{}
+		json.NewDecoder(r.Body).Decode(event)
+		events <- event
+	}))
+	defer ts.Close()
+	m := NewManager()
+	m.init()
+	m.Notify = &Notify{
+		Sinks: []*NotifySink{
+			{Type: SinkWebhook, URL: ts.URL, Groups: []string{"notify*"}, Events: []string{EventRestartLimit, EventUnhealthy}, Retry: 2, Backoff: "10ms"},
+		},
+	}
+	if err := m.Notify.check(); err != nil {
+		t.Error(err)
+		return
+	}
+	m.Groups["notify"] = Group{
+		Name:     "notify",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:    "crash",
+				Path:    "/bin/sh",
+				Args:    []string{"-c", "exit 1"},
+				Restart: &RestartPolicy{Policy: RestartOnFailure, Delay: "10ms", Limit: 2},
+			},
+			{
+				Name:  "unhealthy",
+				Path:  "/bin/sleep",
+				Args:  []string{"10"},
+				Ready: &Probe{Command: "exit 1", Interval: "10ms", Timeout: "50ms"},
+			},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "notify")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	received := map[string]*Event{}
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received[event.Type] = event
+		case <-time.After(5 * time.Second):
+			t.Errorf("%v", toJSON(received))
+			return
+		}
+	}
+	if received[EventRestartLimit].Key != "notify/crash" || received[EventRestartLimit].Exit.Code != 1 || received[EventUnhealthy].Key != "notify/unhealthy" {
+		t.Errorf("%v", toJSON(received))
+		return
+	}
+	if history := m.History("notify/crash"); len(history) != 3 {
+		t.Errorf("%v", toJSON(history))
+		return
+	}
+	//filter and rate limit
+	sink := &NotifySink{Type: SinkExec, Command: "true", Groups: []string{"web"}, Events: []string{EventExit}, RateLimit: "1m"}
+	event := &Event{Type: EventExit, Key: "web/a", Group: "web", Time: time.Now()}
+	if !sink.match(event) || sink.match(&Event{Type: EventExit, Group: "api"}) || sink.match(&Event{Type: EventUnhealthy, Group: "web"}) {
+		t.Error("error")
+		return
+	}
+	if !sink.allow(event) || sink.allow(&Event{Type: EventExit, Key: "web/a", Time: time.Now()}) {
+		t.Error("error")
+		return
+	}
+	next := &Event{Type: EventExit, Key: "web/a", Time: time.Now().Add(2 * time.Minute)}
+	if !sink.allow(next) || next.Suppressed != 1 {
+		t.Errorf("%v", toJSON(next))
+		return
+	}
+	if err = sink.send(event); err != nil {
+		t.Error(err)
+		return
+	}
+	//check
+	if (&NotifySink{Type: "xx"}).check() == nil || (&NotifySink{Type: SinkSMTP}).check() == nil || (&NotifySink{Type: SinkExec, Command: "true", Events: []string{"xx"}}).check() == nil {
+		t.Error("error")
+		return
+	}
+	if (&RestartPolicy{Policy: "xx"}).check() == nil || (&RestartPolicy{Delay: "xx"}).check() == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestRestartStartFail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	events := make(chan *Event, 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := &Event{}
+		json.NewDecoder(r.Body).Decode(event)
+		events <- event
+	}))
+	defer ts.Close()
+	dir, _ := ioutil.TempDir("", "serviced")
+	defer os.RemoveAll(dir)
+	//the script is removed on first run, so restart is fail to start
+	script := filepath.Join(dir, "crash.sh")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\nrm -f $0\nexit 1\n"), 0755)
+	m := NewManager()
+	m.init()
+	m.Notify = &Notify{
+		Sinks: []*NotifySink{
+			{Type: SinkWebhook, URL: ts.URL, Events: []string{EventExit, EventRestartLimit}},
+		},
+	}
+	m.Groups["restart"] = Group{
+		Name:     "restart",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "crash", Path: script, Restart: &RestartPolicy{Policy: RestartOnFailure, Delay: "10ms", Limit: 2}},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "restart")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	received := []*Event{}
+	for len(received) < 4 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Errorf("%v", toJSON(received))
+			return
+		}
+	}
+	types := map[string]int{}
+	for _, event := range received {
+		if event.Type == EventExit && event.Exit.Code == -1 && len(event.Exit.Err) > 0 {
+			types["fail"]++
+		} else {
+			types[event.Type]++
+		}
+	}
+	if types[EventExit] != 1 || types["fail"] != 2 || types[EventRestartLimit] != 1 {
+		t.Errorf("%v", toJSON(received))
+		return
+	}
+	if history := m.History("restart/crash"); len(history) != 3 {
+		t.Errorf("%v", toJSON(history))
+		return
+	}
+}
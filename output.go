@@ -0,0 +1,467 @@
+package serviced
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//DefaultLogFileMode is the default mode of created log file
+const DefaultLogFileMode os.FileMode = 0640
+
+//DefaultLogDirMode is the default mode of created log directory
+const DefaultLogDirMode os.FileMode = 0750
+
+//StreamCombined is the stream name of combined stdout/stderr
+const StreamCombined = "combined"
+
+//DefaultTimestampFormat is the default timestamp layout of log_timestamps
+const DefaultTimestampFormat = "2006-01-02 15:04:05.000"
+
+//OutputDrainTimeout is the max time to wait piped output copied after service exited, the pipe may be kept opened by other process
+var OutputDrainTimeout = time.Second
+
+//lineTail is the writer to keep the last lines
+type lineTail struct {
+	max     int
+	lines   []string
+	partial string
+	locker  sync.Mutex
+}
+
+func newLineTail(max int) (tail *lineTail) {
+	tail = &lineTail{max: max}
+	return
+}
+
+//Write will split data to lines and keep the last max lines
+func (t *lineTail) Write(p []byte) (n int, err error) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	lines := strings.Split(t.partial+string(p), "\n")
+	t.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		t.lines = append(t.lines, strings.TrimSuffix(line, "\r"))
+	}
+	if len(t.lines) > t.max {
+		t.lines = append([]string{}, t.lines[len(t.lines)-t.max:]...)
+	}
+	n = len(p)
+	return
+}
+
+//Lines will return the last lines, the partial line is included
+func (t *lineTail) Lines() (lines []string) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	lines = append(lines, t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > t.max {
+		lines = lines[len(lines)-t.max:]
+	}
+	return
+}
+
+//lineWriter is the writer to split data to lines and call handler on each line without newline,
+//the line longer than max is split to multi line
+type lineWriter struct {
+	max     int
+	partial []byte
+	handler func(line []byte)
+	locker  sync.Mutex
+}
+
+func newLineWriter(max int, handler func(line []byte)) (writer *lineWriter) {
+	writer = &lineWriter{max: max, handler: handler}
+	return
+}
+
+//Write will call handler on each complete line, the incomplete line is kept to next write or flush
+func (l *lineWriter) Write(p []byte) (n int, err error) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	n = len(p)
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			l.partial = append(l.partial, p...)
+			for len(l.partial) >= l.max {
+				l.handler(l.partial[:l.max])
+				l.partial = append([]byte{}, l.partial[l.max:]...)
+			}
+			break
+		}
+		line := p[:idx]
+		if len(l.partial) > 0 {
+			line = append(l.partial, line...)
+			l.partial = nil
+		}
+		for len(line) > l.max {
+			l.handler(line[:l.max])
+			line = line[l.max:]
+		}
+		l.handler(bytes.TrimSuffix(line, []byte("\r")))
+		p = p[idx+1:]
+	}
+	return
+}
+
+//Flush will call handler on the incomplete line
+func (l *lineWriter) Flush() {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	if len(l.partial) > 0 {
+		l.handler(l.partial)
+		l.partial = nil
+	}
+}
+
+//pipeOutput will create the pipe for child output and copy it to out, the writer must be closed after child is started,
+//done is closed when all writer is closed.
+func pipeOutput(out io.Writer) (writer *os.File, done chan int, err error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return
+	}
+	done = make(chan int)
+	go func() {
+		copyOutput(reader, out)
+		reader.Close()
+		close(done)
+	}()
+	return
+}
+
+//copyOutput will copy reader to out until reader is fail, it keep reading when out is fail,
+//so the child is not blocked or broken on writing
+func copyOutput(reader io.Reader, out io.Writer) {
+	buffer := make([]byte, 32*1024)
+	for {
+		n, readErr := reader.Read(buffer)
+		if n > 0 {
+			out.Write(buffer[:n])
+		}
+		if readErr != nil {
+			break
+		}
+	}
+}
+
+//serviceOutput is the stdout/stderr of service, the stdout/stderr file is passed to child directly when it is not consumed by
+//log driver, decorate, echo or interactive attach, otherwise it is piped to keep the last lines of stderr and stream the output
+//to attached client, both is read from pty master on tty
+type serviceOutput struct {
+	//Stdout/Stderr is the output passed to child
+	Stdout     *os.File
+	Stderr     *os.File
+	stdoutFile *os.File
+	stderrFile *os.File
+	stdoutOut  io.Writer
+	fanout     *outputFanout
+	tail       *lineTail
+	sink       logSink
+	lines      []*lineWriter
+	echo       bool
+	done       []chan int
+	piped      []*os.File
+	pid        int32
+	//stderrPath/stderrOffset is the stderr file passed to child directly, the last lines is read from it
+	stderrPath   string
+	stderrOffset int64
+}
+
+//openLogFile will open log file to append, the missing parent directory is created by dirMode and the missing file is created by fileMode,
+//the created file and directory is owned by cred if it is not nil
+func openLogFile(path string, fileMode, dirMode os.FileMode, cred *credential) (file *os.File, err error) {
+	created := []string{}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, e := os.Stat(dir); !os.IsNotExist(e) || dir == filepath.Dir(dir) {
+			break
+		}
+		created = append(created, dir)
+	}
+	if len(created) > 0 {
+		err = os.MkdirAll(filepath.Dir(path), dirMode)
+		if err != nil {
+			err = fmt.Errorf("create log directory fail with %v", err)
+			return
+		}
+	}
+	if _, e := os.Stat(path); os.IsNotExist(e) {
+		created = append([]string{path}, created...)
+	}
+	file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileMode)
+	if err != nil || cred == nil {
+		return
+	}
+	for _, dir := range created {
+		if err = cred.chown(dir); err != nil {
+			err = fmt.Errorf("change owner of %v fail with %v", dir, err)
+			file.Close()
+			return
+		}
+	}
+	return
+}
+
+//openOutput will open the stdout/stderr file and log driver of service, outPath is used to resolve file path,
+//key is used on line prefix, tags is used to tag line on log driver, the log file is owned by cred if it is not nil,
+//the stdout/stderr is copied to the line writer created by echo if it is not nil
+func openOutput(service *Service, outPath func(string) string, key string, tags logLine, cred *credential, echo func() *lineWriter) (output *serviceOutput, err error) {
+	output = &serviceOutput{tail: newLineTail(HistoryLines), fanout: newOutputFanout()}
+	defer func() {
+		if err != nil {
+			output.Close()
+		}
+	}()
+	fileMode, dirMode, err := service.logModes()
+	if err != nil {
+		return
+	}
+	openFile := func(path string) (*os.File, error) {
+		return openLogFile(path, fileMode, dirMode, cred)
+	}
+	stdout, stderr := canonicalPath(outPath(service.Stdout)), canonicalPath(outPath(service.Stderr))
+	if service.CombinedOutput {
+		stderr = stdout
+	}
+	if len(stdout) > 0 {
+		output.stdoutFile, err = openFile(stdout)
+		if err != nil {
+			return
+		}
+	}
+	if len(stderr) > 0 && stderr == stdout {
+		output.stderrFile = output.stdoutFile
+	} else if len(stderr) > 0 {
+		output.stderrFile, err = openFile(stderr)
+		if err != nil {
+			return
+		}
+		if sameFile(output.stdoutFile, output.stderrFile) {
+			//the path is different but file is same by hard link or case insensitive file system
+			output.stderrFile.Close()
+			output.stderrFile = output.stdoutFile
+		}
+	}
+	//the combined output is one stream like 2>&1, so it is not tagged by stream
+	stdoutStream, stderrStream := "stdout", "stderr"
+	if service.CombinedOutput {
+		stdoutStream = StreamCombined
+	}
+	stdoutOut := []io.Writer{output.fanout}
+	stderrOut := []io.Writer{output.fanout, output.tail}
+	if service.CombinedOutput || service.TTY {
+		//stdout/stderr is read from one pipe or pty master
+		stdoutOut = append([]io.Writer{}, stderrOut...)
+	}
+	if output.stdoutFile != nil {
+		stdoutOut = append(stdoutOut, output.decorate(service, output.stdoutFile, key, stdoutStream))
+	}
+	if output.stderrFile != nil && !service.CombinedOutput {
+		stderrOut = append(stderrOut, output.decorate(service, output.stderrFile, key, stderrStream))
+	}
+	if service.LogDriver != nil {
+		output.sink, err = service.LogDriver.open(outPath(service.LogDriver.Path), openFile)
+		if err != nil {
+			return
+		}
+		pid := func() int { return int(atomic.LoadInt32(&output.pid)) }
+		tags.Stream = stdoutStream
+		stdoutLines := logStream(output.sink, tags, pid)
+		tags.Stream = stderrStream
+		stderrLines := logStream(output.sink, tags, pid)
+		output.lines = append(output.lines, stdoutLines, stderrLines)
+		stdoutOut = append(stdoutOut, stdoutLines)
+		stderrOut = append(stderrOut, stderrLines)
+	}
+	if echo != nil {
+		stdoutEcho, stderrEcho := echo(), echo()
+		output.lines = append(output.lines, stdoutEcho, stderrEcho)
+		output.echo = true
+		stdoutOut = append(stdoutOut, stdoutEcho)
+		stderrOut = append(stderrOut, stderrEcho)
+	}
+	output.stdoutOut = io.MultiWriter(stdoutOut...)
+	if service.TTY {
+		return
+	}
+	//the file is written by child directly when nothing else is consuming the output,
+	//so the child is not broken by closed pipe when daemon is exited
+	direct := service.LogDriver == nil && echo == nil && !service.LogTimestamps && !service.LogPrefix && service.Stdin != StdinPipe
+	if direct && output.stdoutFile != nil {
+		output.Stdout = output.stdoutFile
+	} else {
+		output.Stdout, err = output.pipe(output.stdoutOut)
+	}
+	if err == nil && service.CombinedOutput {
+		//the stderr is dup of stdout, so the order is kept by kernel
+		output.Stderr = output.Stdout
+	} else if err == nil && direct && output.stderrFile != nil {
+		output.Stderr = output.stderrFile
+	} else if err == nil {
+		output.Stderr, err = output.pipe(io.MultiWriter(stderrOut...))
+	}
+	if err == nil && output.Stderr == output.stderrFile {
+		output.stderrPath = stderr
+		if info, e := output.stderrFile.Stat(); e == nil {
+			output.stderrOffset = info.Size()
+		}
+	}
+	return
+}
+
+//decorate will return the writer to prepend timestamp and key[stream] prefix on each line by log_timestamps/log_prefix,
+//the prefix is key only on combined output, each line is written by one write, so the stdout/stderr line is not mixed on same file
+func (o *serviceOutput) decorate(service *Service, file *os.File, key, stream string) io.Writer {
+	if !service.LogTimestamps && !service.LogPrefix {
+		return file
+	}
+	layout := service.LogTimestampFormat
+	if len(layout) < 1 {
+		layout = DefaultTimestampFormat
+	}
+	prefix := fmt.Sprintf("%v[%v] ", key, stream)
+	if stream == StreamCombined {
+		prefix = key + " "
+	}
+	writer := newLineWriter(LogMaxLine, func(line []byte) {
+		buffer := bytes.NewBuffer(make([]byte, 0, len(line)+64))
+		if service.LogTimestamps {
+			buffer.WriteString(time.Now().Format(layout))
+			buffer.WriteByte(' ')
+		}
+		if service.LogPrefix {
+			buffer.WriteString(prefix)
+		}
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+		file.Write(buffer.Bytes())
+	})
+	o.lines = append(o.lines, writer)
+	return writer
+}
+
+//canonicalPath will return the absolute path which symlink is resolved, the not existed path is only cleaned
+func canonicalPath(path string) string {
+	if len(path) < 1 {
+		return path
+	}
+	path, _ = filepath.Abs(path)
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	dir, name := filepath.Split(path)
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		return filepath.Join(real, name)
+	}
+	return path
+}
+
+//sameFile will return true if both file is opened and pointing to same file
+func sameFile(a, b *os.File) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	aInfo, aErr := a.Stat()
+	bInfo, bErr := b.Stat()
+	return aErr == nil && bErr == nil && os.SameFile(aInfo, bInfo)
+}
+
+func (o *serviceOutput) pipe(out io.Writer) (writer *os.File, err error) {
+	writer, done, err := pipeOutput(out)
+	if err == nil {
+		o.piped = append(o.piped, writer)
+		o.done = append(o.done, done)
+	}
+	return
+}
+
+//readTTY will copy the output of pty master to stdout writer, the master must be closed after child is exited
+func (o *serviceOutput) readTTY(master *os.File) {
+	done := make(chan int)
+	o.done = append(o.done, done)
+	go func() {
+		copyOutput(master, o.stdoutOut)
+		close(done)
+	}()
+}
+
+//HookOut will return the writer of hook output, it is nil when stdout file, log driver and echo is not configured
+func (o *serviceOutput) HookOut() io.Writer {
+	if o.stdoutFile == nil && o.sink == nil && !o.echo {
+		return nil
+	}
+	return o.stdoutOut
+}
+
+//Started will close the pipe writer of child, it must be called after child is started or fail to start
+func (o *serviceOutput) Started(pid int) {
+	atomic.StoreInt32(&o.pid, int32(pid))
+	for _, writer := range o.piped {
+		writer.Close()
+	}
+	o.piped = nil
+}
+
+//Wait will wait all output is copied in timeout, the output may be still opened by other process
+func (o *serviceOutput) Wait(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for _, done := range o.done {
+		select {
+		case <-done:
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+//StderrLines will return the last lines of stderr, it is read from the file written after started when stderr is passed directly
+func (o *serviceOutput) StderrLines() []string {
+	if len(o.stderrPath) < 1 {
+		return o.tail.Lines()
+	}
+	tail := newLineTail(HistoryLines)
+	file, err := os.Open(o.stderrPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	offset := o.stderrOffset
+	if info, err := file.Stat(); err == nil && info.Size()-offset > int64(LogMaxLine) {
+		offset = info.Size() - int64(LogMaxLine)
+	}
+	file.Seek(offset, io.SeekStart)
+	copyOutput(file, tail)
+	return tail.Lines()
+}
+
+//Close will flush line and close log driver and file
+func (o *serviceOutput) Close() {
+	o.Started(int(atomic.LoadInt32(&o.pid)))
+	for _, line := range o.lines {
+		line.Flush()
+	}
+	if o.sink != nil {
+		if err := o.sink.Close(); err != nil {
+			log.Warnf("close log driver fail with %v", err)
+		}
+	}
+	if o.stdoutFile != nil {
+		o.stdoutFile.Close()
+	}
+	if o.stderrFile != nil && o.stderrFile != o.stdoutFile {
+		o.stderrFile.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDecorateOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-decorate.log")
+	maxLine := LogMaxLine
+	LogMaxLine = 8
+	defer func() { LogMaxLine = maxLine }()
+	m := NewManager()
+	m.init()
+	m.Groups["output"] = Group{
+		Name:     "output",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:               "decorate",
+				Path:               "/bin/sh",
+				Args:               []string{"-c", "printf 'par'; sleep 0.1; echo tial; echo 0123456789ab; sleep 0.1; echo err >&2; printf last"},
+				Stdout:             "test-decorate.log",
+				Stderr:             "test-decorate.log",
+				Type:               ServiceOneshot,
+				LogTimestamps:      true,
+				LogTimestampFormat: "15:04:05",
+				LogPrefix:          true,
+			},
+		},
+	}
+	err := m.StartGroup(ioutil.Discard, "output")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, _ := ioutil.ReadFile("test-decorate.log")
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	expect := []string{
+		"output/decorate[stdout] partial",
+		"output/decorate[stdout] 01234567",
+		"output/decorate[stdout] 89ab",
+		"output/decorate[stderr] err",
+		"output/decorate[stdout] last",
+	}
+	if len(lines) != len(expect) {
+		t.Errorf("%v", string(data))
+		return
+	}
+	for i, line := range lines {
+		if !regexp.MustCompile(`^\d\d:\d\d:\d\d `).MatchString(line) || strings.TrimLeft(line, "0123456789: ") != expect[i] {
+			t.Errorf("%v", string(data))
+			return
+		}
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-same.log")
+	defer os.Remove("test-link.log")
+	os.Symlink("test-same.log", "test-link.log")
+	resolve := func(path string) string { return path }
+	for _, stderr := range []string{"./test-same.log", "test-link.log", "xx/../test-same.log"} {
+		output, err := openOutput(&Service{Stdout: "test-same.log", Stderr: stderr}, resolve, "k", logLine{}, nil, nil)
+		if err != nil || output.stdoutFile == nil || output.stderrFile != output.stdoutFile {
+			t.Errorf("%v,%v", stderr, err)
+			return
+		}
+		output.Close()
+	}
+	//combined
+	defer os.Remove("test-combined.log")
+	m := NewManager()
+	m.init()
+	m.Groups["output"] = Group{
+		Name:     "output",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:           "combined",
+				Path:           "/bin/sh",
+				Args:           []string{"-c", "echo a; echo b >&2; echo c; echo d >&2; echo e"},
+				Stdout:         "./test-combined.log",
+				Type:           ServiceOneshot,
+				CombinedOutput: true,
+			},
+			{
+				Name:           "prefix",
+				Path:           "/bin/sh",
+				Args:           []string{"-c", "echo a; echo b >&2; echo c"},
+				Stdout:         "./test-combined-prefix.log",
+				Type:           ServiceOneshot,
+				CombinedOutput: true,
+				LogPrefix:      true,
+			},
+		},
+	}
+	defer os.Remove("test-combined-prefix.log")
+	err := m.StartGroup(ioutil.Discard, "output")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, _ := ioutil.ReadFile("test-combined.log")
+	if string(data) != "a\nb\nc\nd\ne\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	data, _ = ioutil.ReadFile("test-combined-prefix.log")
+	if string(data) != "output/prefix a\noutput/prefix b\noutput/prefix c\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	if (&Service{Name: "a", Path: "a", CombinedOutput: true}).check() == nil || (&Service{Name: "a", Path: "a", Stdout: "a", Stderr: "b", CombinedOutput: true}).check() == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestOpenLogFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.RemoveAll("test-logdir")
+	file, err := openLogFile("test-logdir/a/b/test.log", 0600, 0700, &credential{UID: uint32(os.Getuid()), GID: uint32(os.Getgid())})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	file.Close()
+	for path, mode := range map[string]os.FileMode{"test-logdir": 0700, "test-logdir/a/b": 0700, "test-logdir/a/b/test.log": 0600} {
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm() != mode {
+			t.Errorf("%v,%v,%v", path, info, err)
+			return
+		}
+	}
+	//modes
+	if (&Service{Name: "a", Path: "a", LogFileMode: "999"}).check() == nil || (&Service{Name: "a", Path: "a", LogDirMode: "1777"}).check() == nil {
+		t.Error("error")
+		return
+	}
+	fileMode, dirMode, err := (&Service{LogFileMode: "0644"}).logModes()
+	if err != nil || fileMode != 0644 || dirMode != DefaultLogDirMode {
+		t.Errorf("%v,%v,%v", fileMode, dirMode, err)
+		return
+	}
+}
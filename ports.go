@@ -0,0 +1,148 @@
+package serviced
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Port is the port allocated by manager and exposed as ${PORT_<NAME>}, the free port is allocated from range like 8000-8100,
+//it is allocated by system when range is empty
+type Port struct {
+	Name  string `json:"name"`
+	Range string `json:"range"`
+}
+
+//portRange will return the min/max port of range, it is 0/0 when range is empty
+func (p *Port) portRange() (min, max int, err error) {
+	if len(p.Range) < 1 {
+		return
+	}
+	parts := strings.SplitN(p.Range, "-", 2)
+	min, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	max = min
+	if err == nil && len(parts) > 1 {
+		max, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	if err != nil || min < 1 || max > 65535 || min > max {
+		err = fmt.Errorf("port %v range %v is invalid", p.Name, p.Range)
+	}
+	return
+}
+
+//portValue will return the value name of port like PORT_HTTP
+func portValue(name string) string {
+	return "PORT_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
+//checkPorts will check the port name and range
+func checkPorts(ports []*Port) (err error) {
+	names := map[string]bool{}
+	for _, port := range ports {
+		if len(port.Name) < 1 {
+			err = fmt.Errorf("port name is required")
+			return
+		}
+		if names[portValue(port.Name)] {
+			err = fmt.Errorf("port %v is duplicated", port.Name)
+			return
+		}
+		names[portValue(port.Name)] = true
+		_, _, err = port.portRange()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+//freePort will return true if the tcp port can be listened
+func freePort(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+//allocPorts will return the ports of service instance by key, the allocated port is kept and reused on restart
+func (m *Manager) allocPorts(key string, ports []*Port) (allocated map[string]int, err error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	used := map[int]bool{}
+	for _, assigned := range m.ports {
+		for _, port := range assigned {
+			used[port] = true
+		}
+	}
+	allocated = map[string]int{}
+	for _, port := range ports {
+		min, max, _ := port.portRange()
+		if assigned, ok := m.ports[key][port.Name]; ok && (min < 1 || (assigned >= min && assigned <= max)) {
+			allocated[port.Name] = assigned
+			continue
+		}
+		if min < 1 {
+			//the system port may be allocated to other service which is not running
+			for i := 0; i < 10 && allocated[port.Name] < 1; i++ {
+				var listener net.Listener
+				listener, err = net.Listen("tcp", ":0")
+				if err != nil {
+					err = fmt.Errorf("allocate port %v fail with %v", port.Name, err)
+					return
+				}
+				if p := listener.Addr().(*net.TCPAddr).Port; !used[p] {
+					allocated[port.Name] = p
+				}
+				listener.Close()
+			}
+			if allocated[port.Name] < 1 {
+				err = fmt.Errorf("allocate port %v fail with no free port", port.Name)
+				return
+			}
+		} else {
+			for p := min; p <= max; p++ {
+				if !used[p] && freePort(p) {
+					allocated[port.Name] = p
+					break
+				}
+			}
+			if allocated[port.Name] < 1 {
+				err = fmt.Errorf("allocate port %v fail with no free port in %v", port.Name, port.Range)
+				return
+			}
+		}
+		used[allocated[port.Name]] = true
+	}
+	m.ports[key] = allocated
+	return
+}
+
+//releasePorts will release the allocated port of service instance matched by match
+func (m *Manager) releasePorts(match func(key string) bool) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for key := range m.ports {
+		if match(key) {
+			delete(m.ports, key)
+		}
+	}
+}
+
+//formatPorts will format ports like http=8080,admin=8081 sorted by name
+func formatPorts(ports map[string]int) string {
+	items := []string{}
+	for name, port := range ports {
+		items = append(items, fmt.Sprintf("%v=%v", name, port))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,84 @@
+package serviced
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPorts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-ports_0.log")
+	defer os.Remove("test-ports_1.log")
+	m := NewManager()
+	m.init()
+	m.Groups["ports"] = Group{
+		Name:     "ports",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:      "web",
+				Path:      "/bin/sh",
+				Args:      []string{"-c", "echo $HTTP ${PORT_ADMIN_API}; sleep 3"},
+				Env:       []string{"HTTP=${PORT_HTTP}"},
+				Stdout:    "test-ports_${INSTANCE}.log",
+				Instances: 2,
+				Ports:     []*Port{{Name: "http"}, {Name: "admin-api", Range: "38000-38100"}},
+			},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "ports")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(100 * time.Millisecond)
+	status := m.Status("ports")
+	if len(status) != 2 || status[0].Ports["http"] < 1 || status[0].Ports["http"] == status[1].Ports["http"] ||
+		status[0].Ports["admin-api"] < 38000 || status[0].Ports["admin-api"] == status[1].Ports["admin-api"] {
+		t.Errorf("%v", toJSON(status))
+		return
+	}
+	ports := status[0].Ports
+	if data, _ := ioutil.ReadFile("test-ports_0.log"); string(data) != fmt.Sprintf("%v %v\n", ports["http"], ports["admin-api"]) {
+		t.Errorf("%v", string(data))
+		return
+	}
+	//keep on restart
+	err = m.Restart(ioutil.Discard, "ports/web@0")
+	if err != nil || formatPorts(m.Status("ports/web@0")[0].Ports) != formatPorts(ports) {
+		t.Errorf("%v,%v", err, toJSON(m.Status("ports/web@0")))
+		return
+	}
+	buffer := bytes.NewBuffer(nil)
+	WriteStatus(buffer, status, FormatTable, "")
+	if !strings.Contains(buffer.String(), "PORTS") || !strings.Contains(buffer.String(), formatPorts(ports)) {
+		t.Errorf("%v", buffer.String())
+		return
+	}
+	//release on scale
+	err = m.Scale("ports", "web", 1)
+	if err != nil || len(m.ports) != 1 {
+		t.Errorf("%v,%v", err, m.ports)
+		return
+	}
+	//check
+	for _, ports := range [][]*Port{{{}}, {{Name: "a"}, {Name: "A"}}, {{Name: "a", Range: "x"}}, {{Name: "a", Range: "100-10"}}, {{Name: "a", Range: "1-70000"}}} {
+		if (&Service{Name: "a", Path: "a", Ports: ports}).check() == nil {
+			t.Errorf("%v", toJSON(ports))
+			return
+		}
+	}
+	if _, err := m.allocPorts("x", []*Port{{Name: "a", Range: "38000"}, {Name: "b", Range: "38000"}}); err == nil {
+		t.Error("error")
+		return
+	}
+}
@@ -0,0 +1,158 @@
+package serviced
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//Probe is struct to record service readiness probe, service is ready after delay when no tcp/http/command is configured
+type Probe struct {
+	TCP      string `json:"tcp"`
+	HTTP     string `json:"http"`
+	Command  string `json:"command"`
+	Delay    string `json:"delay"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout"`
+}
+
+//durations will parse delay/interval/timeout, default interval is 1s and timeout is 30s
+func (p *Probe) durations() (delay, interval, timeout time.Duration, err error) {
+	interval, timeout = time.Second, 30*time.Second
+	parse := func(name, val string, dur *time.Duration) {
+		if err != nil || len(val) < 1 {
+			return
+		}
+		*dur, err = time.ParseDuration(val)
+		if err == nil && *dur < 0 {
+			err = fmt.Errorf("must be positive")
+		}
+		if err != nil {
+			err = fmt.Errorf("ready %v %v is invalid with %v", name, val, err)
+		}
+	}
+	parse("delay", p.Delay, &delay)
+	parse("interval", p.Interval, &interval)
+	parse("timeout", p.Timeout, &timeout)
+	return
+}
+
+//check will check probe configure
+func (p *Probe) check() (err error) {
+	_, _, _, err = p.durations()
+	return
+}
+
+//probe will check once if service is ready
+func (p *Probe) probe(hook *hookRunner) (err error) {
+	if len(p.TCP) > 0 {
+		var conn net.Conn
+		conn, err = net.DialTimeout("tcp", envReplaceEmpty(hook.Values, p.TCP, false), time.Second)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}
+	if len(p.HTTP) > 0 {
+		client := &http.Client{Timeout: 3 * time.Second}
+		var res *http.Response
+		res, err = client.Get(envReplaceEmpty(hook.Values, p.HTTP, false))
+		if err != nil {
+			return
+		}
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode >= 400 {
+			err = fmt.Errorf("http status code is %v", res.StatusCode)
+			return
+		}
+	}
+	if len(p.Command) > 0 {
+		command := envReplaceEmpty(hook.Values, p.Command, false)
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("/bin/sh", "-c", command)
+		}
+		cmd.Dir = hook.Dir
+		cmd.Env = hook.Env
+		if hook.Cred != nil {
+			hook.Cred.apply(cmd)
+		}
+		err = cmd.Run()
+	}
+	return
+}
+
+//runProbe will probe service until it is ready or exited
+func (m *Manager) runProbe(running *Running) {
+	probe := running.Service.Ready
+	if probe == nil {
+		m.setReady(running)
+		return
+	}
+	delay, interval, timeout, _ := probe.durations()
+	select {
+	case <-running.exited:
+		return
+	case <-time.After(delay):
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := probe.probe(running.hook)
+		if err == nil {
+			log.Infof("%v is ready", running.Key)
+			m.setReady(running)
+			return
+		}
+		log.Debugf("%v is not ready with %v", running.Key, err)
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			//notify once and keep probing
+			deadline = time.Time{}
+			message := fmt.Sprintf("%v health check is fail in %v with %v", running.Key, timeout, err)
+			log.Warnf("%v", message)
+			m.emit(newEvent(EventUnhealthy, running, message, nil))
+		}
+		select {
+		case <-running.exited:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (m *Manager) setReady(running *Running) {
+	m.locker.Lock()
+	if running.State == StateRunning {
+		running.State = StateReady
+		close(running.ready)
+		m.notify()
+	}
+	m.locker.Unlock()
+}
+
+//waitReady will wait service to be ready by probe delay and timeout
+func (m *Manager) waitReady(running *Running) (err error) {
+	timeout := 30 * time.Second
+	if running.Service.Ready != nil {
+		var delay time.Duration
+		delay, _, timeout, _ = running.Service.Ready.durations()
+		//the probe is started after delay
+		timeout += delay
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-running.ready:
+	case <-running.exited:
+		err = fmt.Errorf("%v is exited with %v", running.Key, running.Err)
+	case <-timer.C:
+		err = fmt.Errorf("%v is not ready after %v", running.Key, timeout)
+	}
+	return
+}
@@ -0,0 +1,171 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//ProxyFailDelay is the time to skip the backend after it is fail on connecting
+var ProxyFailDelay = 5 * time.Second
+
+//Proxy is the tcp proxy of group, it listen on listen address and forward to backend of ready service instance by round-robin,
+//the backend is address like 127.0.0.1:${PORT_HTTP} which is replaced by instance values
+type Proxy struct {
+	Listen  string `json:"listen"`
+	Service string `json:"service"`
+	Backend string `json:"backend"`
+}
+
+//check will check proxy configure and the service is in group
+func (p *Proxy) check(group *Group) (err error) {
+	if len(p.Listen) < 1 || len(p.Service) < 1 || len(p.Backend) < 1 {
+		err = fmt.Errorf("proxy listen/service/backend is required")
+		return
+	}
+	for _, service := range group.Services {
+		if service.Name == p.Service {
+			return
+		}
+	}
+	err = fmt.Errorf("proxy service %v is not exist", p.Service)
+	return
+}
+
+//groupProxy is the running proxy of group, it is kept across group restarts
+type groupProxy struct {
+	Proxy
+	group    string
+	listener net.Listener
+	next     int
+	failed   map[string]time.Time
+	locker   sync.Mutex
+}
+
+//startProxy will start the proxy of group if it is not started, it is restarted when the configure is changed
+func (m *Manager) startProxy(group *Group) (err error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	proxy := m.proxies[group.Name]
+	if proxy != nil && group.Proxy != nil && proxy.Proxy == *group.Proxy {
+		return
+	}
+	if proxy != nil {
+		log.Infof("%v proxy on %v is closed by configure is changed", group.Name, proxy.Listen)
+		proxy.listener.Close()
+		delete(m.proxies, group.Name)
+	}
+	if group.Proxy == nil {
+		return
+	}
+	listener, err := net.Listen("tcp", group.Proxy.Listen)
+	if err != nil {
+		err = fmt.Errorf("proxy listen on %v fail with %v", group.Proxy.Listen, err)
+		return
+	}
+	proxy = &groupProxy{
+		Proxy:    *group.Proxy,
+		group:    group.Name,
+		listener: listener,
+		failed:   map[string]time.Time{},
+	}
+	m.proxies[group.Name] = proxy
+	log.Infof("%v proxy is listening on %v to %v", group.Name, listener.Addr(), proxy.Service)
+	go m.runProxy(proxy)
+	return
+}
+
+//CloseProxy will close the proxy of group, group * is all
+func (m *Manager) CloseProxy(group string) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for name, proxy := range m.proxies {
+		if group == "*" || name == group {
+			log.Infof("%v proxy on %v is closed", name, proxy.Listen)
+			proxy.listener.Close()
+			delete(m.proxies, name)
+		}
+	}
+}
+
+func (m *Manager) runProxy(proxy *groupProxy) {
+	for {
+		conn, err := proxy.listener.Accept()
+		if err != nil {
+			log.Infof("%v proxy is stopped by %v", proxy.group, err)
+			break
+		}
+		go m.proxyConn(proxy, conn)
+	}
+}
+
+//backends will return the backend address of ready instance, it is started from next backend by round-robin
+func (m *Manager) backends(proxy *groupProxy) (backends []string) {
+	m.locker.RLock()
+	for _, running := range m.running {
+		if running.Group.Name == proxy.group && running.Service.Name == proxy.Service && running.State == StateReady {
+			backends = append(backends, envReplaceEmpty(running.hook.Values, proxy.Backend, false))
+		}
+	}
+	m.locker.RUnlock()
+	sort.Strings(backends)
+	proxy.locker.Lock()
+	defer proxy.locker.Unlock()
+	if len(backends) > 0 {
+		proxy.next = (proxy.next + 1) % len(backends)
+		backends = append(append([]string{}, backends[proxy.next:]...), backends[:proxy.next]...)
+	}
+	//the fail backend is tried last
+	now := time.Now()
+	healthy, failed := []string{}, []string{}
+	for _, backend := range backends {
+		if now.Sub(proxy.failed[backend]) < ProxyFailDelay {
+			failed = append(failed, backend)
+		} else {
+			healthy = append(healthy, backend)
+		}
+	}
+	backends = append(healthy, failed...)
+	return
+}
+
+func (m *Manager) proxyConn(proxy *groupProxy, conn net.Conn) {
+	defer conn.Close()
+	var backend net.Conn
+	var err error
+	for _, address := range m.backends(proxy) {
+		backend, err = net.DialTimeout("tcp", address, 3*time.Second)
+		if err == nil {
+			proxy.locker.Lock()
+			delete(proxy.failed, address)
+			proxy.locker.Unlock()
+			break
+		}
+		log.Warnf("%v proxy connect to %v fail with %v", proxy.group, address, err)
+		proxy.locker.Lock()
+		proxy.failed[address] = time.Now()
+		proxy.locker.Unlock()
+	}
+	if backend == nil {
+		log.Warnf("%v proxy is not having available backend of %v", proxy.group, proxy.Service)
+		return
+	}
+	defer backend.Close()
+	done := make(chan int, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		done <- 1
+	}
+	go pipe(backend, conn)
+	go pipe(conn, backend)
+	<-done
+	<-done
+}
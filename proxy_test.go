@@ -0,0 +1,100 @@
+package serviced
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["proxy"] = Group{
+		Name:     "proxy",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "web", Path: "/bin/sleep", Args: []string{"10"}, Instances: 3, Ports: []*Port{{Name: "http"}}},
+		},
+		Proxy: &Proxy{Listen: "127.0.0.1:0", Service: "web", Backend: "127.0.0.1:${PORT_HTTP}"},
+	}
+	defer m.CloseProxy("*")
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "proxy")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	//the backend server of instance 0/1, instance 2 is not listening
+	for instance := 0; instance < 2; instance++ {
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%v", m.ports[fmt.Sprintf("proxy/web@%v", instance)]["http"]))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer listener.Close()
+		go func(instance int) {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					break
+				}
+				fmt.Fprintf(conn, "%v", instance)
+				conn.Close()
+			}
+		}(instance)
+	}
+	err = m.Wait("proxy", WaitReady, time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	address := m.proxies["proxy"].listener.Addr().String()
+	request := func() string {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return err.Error()
+		}
+		defer conn.Close()
+		data, _ := ioutil.ReadAll(conn)
+		return string(data)
+	}
+	received := map[string]int{}
+	for i := 0; i < 6; i++ {
+		received[request()]++
+	}
+	if len(received) != 2 || received["0"] < 2 || received["1"] < 2 {
+		t.Errorf("%v", received)
+		return
+	}
+	//keep listening on restart
+	m.Restart(ioutil.Discard, "proxy")
+	if m.proxies["proxy"].listener.Addr().String() != address {
+		t.Error("error")
+		return
+	}
+	m.StopAll()
+	if data := request(); data != "" {
+		t.Errorf("%v", data)
+		return
+	}
+	m.CloseProxy("proxy")
+	if _, err := net.Dial("tcp", address); err == nil {
+		t.Error("error")
+		return
+	}
+	//check
+	for _, proxy := range []*Proxy{{}, {Listen: ":80", Service: "none", Backend: "x"}} {
+		g := m.Groups["proxy"]
+		g.Proxy = proxy
+		if g.check() == nil {
+			t.Errorf("%v", toJSON(proxy))
+			return
+		}
+	}
+}
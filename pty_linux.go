@@ -0,0 +1,53 @@
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+//openPty will open the pseudo terminal with 80x24 size, the master is kept by manager and the slave is passed to child
+func openPty() (master, slave *os.File, err error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		err = fmt.Errorf("open pty fail with %v", err)
+		return
+	}
+	//the non blocking master is using runtime poller, so it can be closed when reading
+	master = os.NewFile(uintptr(fd), "/dev/ptmx")
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	var n int
+	if err == nil {
+		n, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	}
+	if err == nil {
+		err = unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: 24, Col: 80})
+	}
+	if err == nil {
+		slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	}
+	if err != nil {
+		master.Close()
+		err = fmt.Errorf("open pty fail with %v", err)
+	}
+	return
+}
+
+//applyTTY will start the command in new session with slave as controlling terminal
+func applyTTY(cmd *exec.Cmd, slave *os.File) {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
+
+//checkTTYSupported will check if tty is supported on current host
+func checkTTYSupported() (err error) {
+	return
+}
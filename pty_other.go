@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+//openPty will return error because tty is only supported on linux
+func openPty() (master, slave *os.File, err error) {
+	err = checkTTYSupported()
+	return
+}
+
+//applyTTY will do nothing because tty is only supported on linux
+func applyTTY(cmd *exec.Cmd, slave *os.File) {
+}
+
+//checkTTYSupported will return error because tty is only supported on linux
+func checkTTYSupported() (err error) {
+	err = fmt.Errorf("tty is not supported on %v", runtime.GOOS)
+	return
+}
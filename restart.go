@@ -0,0 +1,170 @@
+package serviced
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//RestartNo is the policy to never restart service
+	RestartNo = "no"
+	//RestartOnFailure is the policy to restart service when it exit with non-zero code or signal
+	RestartOnFailure = "on-failure"
+	//RestartAlways is the policy to restart service whenever it exit by itself
+	RestartAlways = "always"
+)
+
+//RestartPolicy is the policy to restart service when it exit by itself
+type RestartPolicy struct {
+	Policy string `json:"policy"`
+	Delay  string `json:"delay"`
+	Limit  int    `json:"limit"`
+	Window string `json:"window"`
+}
+
+//durations will return delay/window with default 1s/1m
+func (r *RestartPolicy) durations() (delay, window time.Duration, err error) {
+	delay, window = time.Second, time.Minute
+	if len(r.Delay) > 0 {
+		delay, err = time.ParseDuration(r.Delay)
+		if err != nil {
+			err = fmt.Errorf("parse restart delay %v fail with %v", r.Delay, err)
+			return
+		}
+	}
+	if len(r.Window) > 0 {
+		window, err = time.ParseDuration(r.Window)
+		if err != nil {
+			err = fmt.Errorf("parse restart window %v fail with %v", r.Window, err)
+			return
+		}
+	}
+	return
+}
+
+//check will check restart policy configure
+func (r *RestartPolicy) check() (err error) {
+	switch r.Policy {
+	case "", RestartNo, RestartOnFailure, RestartAlways:
+	default:
+		err = fmt.Errorf("restart policy must be %v, %v or %v", RestartNo, RestartOnFailure, RestartAlways)
+		return
+	}
+	if r.Limit < 0 {
+		err = fmt.Errorf("restart limit must be positive")
+		return
+	}
+	_, _, err = r.durations()
+	return
+}
+
+//autoRestart will restart the service exited by itself after delay by policy,
+//the restart limit reached event is emitted when it restart more than limit times in window
+func (m *Manager) autoRestart(running *Running, exit *Exit) {
+	policy := running.Service.Restart
+	if policy == nil || exit.Requested || len(running.Service.Schedule) > 0 {
+		return
+	}
+	if policy.Policy != RestartAlways && (policy.Policy != RestartOnFailure || !exit.Crashed()) {
+		return
+	}
+	delay, window, _ := policy.durations()
+	now := time.Now()
+	m.locker.Lock()
+	restarted := []time.Time{}
+	for _, last := range m.restarted[running.Key] {
+		if now.Sub(last) < window {
+			restarted = append(restarted, last)
+		}
+	}
+	if policy.Limit > 0 && len(restarted) >= policy.Limit {
+		delete(m.restarted, running.Key)
+		m.locker.Unlock()
+		message := fmt.Sprintf("%v restart limit %v in %v is reached", running.Key, policy.Limit, window)
+		log.Warnf("%v", message)
+		m.emit(newEvent(EventRestartLimit, running, message, exit))
+		return
+	}
+	m.restarted[running.Key] = append(restarted, now)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.locker.RLock()
+		waiting := m.restarts[running.Key] == timer
+		m.locker.RUnlock()
+		if waiting {
+			m.restartExited(running)
+		}
+		//the restart is removed after started, so it is always waiting or running
+		m.locker.Lock()
+		if m.restarts[running.Key] == timer {
+			delete(m.restarts, running.Key)
+			m.notify()
+		}
+		m.locker.Unlock()
+	})
+	m.restarts[running.Key] = timer
+	m.locker.Unlock()
+	log.Infof("%v will be restarted after %v by %v policy", running.Key, delay, policy.Policy)
+}
+
+//restartExited will start the exited service again if it is not removed, the start failure is handled as exit,
+//so it is notified and restarted again by policy
+func (m *Manager) restartExited(running *Running) {
+	m.locker.Lock()
+	group, ok := m.Groups[running.Group.Name]
+	var service *Service
+	for i := range group.Services {
+		if group.Services[i].Name == running.Service.Name {
+			service = &group.Services[i]
+		}
+	}
+	if !ok || service == nil || running.Instance >= m.scaledCount(&group, service) {
+		m.locker.Unlock()
+		log.Infof("%v restart is skipped by it is removed", running.Key)
+		return
+	}
+	m.locker.Unlock()
+	log.Infof("%v is restarting", running.Key)
+	started := time.Now()
+	_, err := m.startService(&group, service, running.Instance)
+	if err == nil {
+		return
+	}
+	log.Warnf("%v restart fail with %v", running.Key, err)
+	failed := &Running{
+		State:    StateStopped,
+		Key:      running.Key,
+		Instance: running.Instance,
+		Group:    &group,
+		Service:  service,
+		Err:      err,
+		Started:  started,
+	}
+	exit := newExit(failed, time.Now(), nil)
+	m.locker.Lock()
+	if m.running[running.Key] == nil {
+		m.exits[running.Key] = failed
+	}
+	m.addHistory(exit)
+	m.notify()
+	m.locker.Unlock()
+	m.emit(newEvent(EventExit, failed, fmt.Sprintf("%v is restart fail with %v", running.Key, err), exit))
+	m.autoRestart(failed, exit)
+}
+
+//cancelRestart will cancel the waiting restart which key is matched, it return the canceled count
+func (m *Manager) cancelRestart(match func(key string) bool) (canceled int) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for key, timer := range m.restarts {
+		if match(key) {
+			timer.Stop()
+			delete(m.restarts, key)
+			delete(m.restarted, key)
+			canceled++
+		}
+	}
+	return
+}
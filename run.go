@@ -0,0 +1,123 @@
+package serviced
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//StopTimeout is the max time to wait service exiting by StopSignal, the service is killed after timeout
+var StopTimeout = 10 * time.Second
+
+//outputColors is the ANSI color of key prefix on Output
+var outputColors = []int{36, 33, 32, 35, 34, 96, 93, 92, 95, 94}
+
+//LoadGroup will load single group file without main configure, it is used to run group in foreground
+func (m *Manager) LoadGroup(filename string) (group *Group, err error) {
+	m.init()
+	filename, err = filepath.Abs(filename)
+	if err != nil {
+		return
+	}
+	group = &Group{}
+	err = unmarshal(filename, group)
+	if err != nil {
+		err = fmt.Errorf("load group from %v fail with %v", filename, err)
+		return
+	}
+	group.Filename = filename
+	group.Enable = 1
+	err = group.check()
+	if err != nil {
+		err = fmt.Errorf("%v from %v", err, filename)
+		return
+	}
+	if old, ok := m.Groups[group.Name]; ok {
+		err = fmt.Errorf("group %v is exists from %v", group.Name, old.Filename)
+		return
+	}
+	m.Groups[group.Name] = *group
+	log.Infof("load group from %v with %v service", filename, len(group.Services))
+	return
+}
+
+//Run will start all loaded group and block until all service is exited by itself or stop is received,
+//the running service is stopped by the received signal and killed after StopTimeout,
+//it return error when any service is start fail or exited with non-zero code or signal
+func (m *Manager) Run(stop <-chan os.Signal) (err error) {
+	err = m.StartAll(ioutil.Discard)
+	for err == nil {
+		m.locker.RLock()
+		idle := len(m.running) < 1 && len(m.restarts) < 1 && len(m.schedules) < 1
+		changed := m.changed
+		m.locker.RUnlock()
+		if idle {
+			break
+		}
+		select {
+		case <-changed:
+		case sig := <-stop:
+			log.Infof("all service is stopping by %v", sig)
+			m.locker.Lock()
+			m.StopSignal = sig
+			m.locker.Unlock()
+			m.StopAll()
+		}
+	}
+	m.StopAll()
+	m.CloseSockets("*")
+	m.CloseProxy("*")
+	if err == nil {
+		if failed := m.failed(); len(failed) > 0 {
+			err = fmt.Errorf("%v is fail", strings.Join(failed, ", "))
+		}
+	}
+	return
+}
+
+//failed will return the sorted key of service which is exited by itself with non-zero code or signal
+func (m *Manager) failed() (keys []string) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	for key, history := range m.history {
+		for _, exit := range history {
+			if exit.Crashed() {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+//echo will return the line writer factory to copy service output to Output with key prefix, it is nil when Output is not set
+func (m *Manager) echo(key string) func() *lineWriter {
+	if m.Output == nil {
+		return nil
+	}
+	prefix := key + " | "
+	if m.Color {
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		prefix = fmt.Sprintf("\x1b[%vm%v\x1b[0m", outputColors[hash.Sum32()%uint32(len(outputColors))], prefix)
+	}
+	out := m.Output
+	return func() *lineWriter {
+		return newLineWriter(LogMaxLine, func(line []byte) {
+			buffer := bytes.NewBuffer(make([]byte, 0, len(prefix)+len(line)+1))
+			buffer.WriteString(prefix)
+			buffer.Write(line)
+			buffer.WriteByte('\n')
+			out.Write(buffer.Bytes())
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package serviced
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+//applyProcessGroup will start the command in new process group, so the terminal signal is not sent to service directly
+func applyProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
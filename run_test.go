@@ -0,0 +1,85 @@
+//go:build !windows
+// +build !windows
+
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	defer os.Remove("test-run.json")
+	//exited by itself
+	ioutil.WriteFile("test-run.json", []byte(`{"name":"run","services":[
+		{"name":"ok","path":"/bin/sh","args":["-c","echo ok; echo warn >&2; printf partial"]},
+		{"name":"bad","path":"/bin/sh","args":["-c","exit 3"]}
+	]}`), os.ModePerm)
+	out := &lockedBuffer{}
+	m := NewManager()
+	m.Output = out
+	_, err := m.LoadGroup("test-run.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = m.LoadGroup("test-run.json"); err == nil {
+		t.Error("error")
+		return
+	}
+	err = m.Run(make(chan os.Signal))
+	if err == nil || !strings.Contains(err.Error(), "run/bad") || strings.Contains(err.Error(), "run/ok") {
+		t.Errorf("%v", err)
+		return
+	}
+	for _, line := range []string{"run/ok | ok\n", "run/ok | warn\n", "run/ok | partial\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("%v", out.String())
+			return
+		}
+	}
+	//stopped by signal
+	ioutil.WriteFile("test-run.json", []byte(`{"name":"run","services":[
+		{"name":"web","path":"/bin/sh","args":["-c","trap 'echo bye; exit 0' INT; echo up; while true; do sleep 0.1; done"]},
+		{"name":"stubborn","path":"/bin/sh","args":["-c","trap '' INT; while true; do sleep 0.1; done"]}
+	]}`), os.ModePerm)
+	StopTimeout = 500 * time.Millisecond
+	defer func() { StopTimeout = 10 * time.Second }()
+	out = &lockedBuffer{}
+	m = NewManager()
+	m.Output = out
+	m.Color = true
+	m.Foreground = true
+	_, err = m.LoadGroup("test-run.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	stop := make(chan os.Signal, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		stop <- syscall.SIGINT
+	}()
+	begin := time.Now()
+	err = m.Run(stop)
+	if err != nil || time.Since(begin) > 2*time.Second {
+		t.Errorf("%v,%v", err, time.Since(begin))
+		return
+	}
+	if !strings.Contains(out.String(), "run/web | \x1b[0mbye\n") {
+		t.Errorf("%q", out.String())
+		return
+	}
+	if exit := m.History("run/web")[0]; exit.Code != 0 || len(exit.Signal) > 0 || !exit.Requested {
+		t.Error(toJSON(exit))
+		return
+	}
+	if exit := m.History("run/stubborn")[0]; exit.Signal != "killed" || !exit.Requested {
+		t.Error(toJSON(exit))
+		return
+	}
+}
@@ -0,0 +1,9 @@
+package serviced
+
+import (
+	"os/exec"
+)
+
+//applyProcessGroup will do nothing on windows, the console signal is not forwarded by manager
+func applyProcessGroup(cmd *exec.Cmd) {
+}
@@ -0,0 +1,89 @@
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+//sandboxNamespaces is the supported namespace which can be created for service
+var sandboxNamespaces = []string{"pid", "mount", "network", "ipc"}
+
+//Sandbox is struct to record service isolation options
+type Sandbox struct {
+	Umask      string   `json:"umask"`
+	Chroot     string   `json:"chroot"`
+	Namespaces []string `json:"namespaces"`
+	ReadOnly   []string `json:"read_only"`
+	NoNewPrivs bool     `json:"no_new_privs"`
+}
+
+//enabled will return true if any sandbox option is configured
+func (s *Sandbox) enabled() bool {
+	return len(s.Umask) > 0 || len(s.Chroot) > 0 || len(s.Namespaces) > 0 || len(s.ReadOnly) > 0 || s.NoNewPrivs
+}
+
+//umask will parse umask from octal string, it return nil if not configured
+func (s *Sandbox) umask() (umask *int, err error) {
+	if len(s.Umask) < 1 {
+		return
+	}
+	val, err := strconv.ParseUint(s.Umask, 8, 32)
+	if err != nil || val > 0777 {
+		err = fmt.Errorf("sandbox umask %v is invalid", s.Umask)
+		return
+	}
+	umask = new(int)
+	*umask = int(val)
+	return
+}
+
+//hasNamespace will return true if namespace is configured
+func (s *Sandbox) hasNamespace(name string) bool {
+	for _, ns := range s.Namespaces {
+		if ns == name {
+			return true
+		}
+	}
+	return false
+}
+
+//check will check sandbox configure
+func (s *Sandbox) check() (err error) {
+	if !s.enabled() {
+		return
+	}
+	_, err = s.umask()
+	if err != nil {
+		return
+	}
+	for _, ns := range s.Namespaces {
+		supported := false
+		for _, name := range sandboxNamespaces {
+			supported = supported || ns == name
+		}
+		if !supported {
+			err = fmt.Errorf("sandbox namespace %v is not supported, supported is %v", ns, sandboxNamespaces)
+			return
+		}
+	}
+	if len(s.Chroot) > 0 {
+		if info, e := os.Stat(s.Chroot); !filepath.IsAbs(s.Chroot) || e != nil || !info.IsDir() {
+			err = fmt.Errorf("sandbox chroot %v must be absolute path to exists folder", s.Chroot)
+			return
+		}
+	}
+	for _, path := range s.ReadOnly {
+		if _, e := os.Stat(path); !filepath.IsAbs(path) || e != nil {
+			err = fmt.Errorf("sandbox read only path %v must be absolute path to exists file/folder", path)
+			return
+		}
+	}
+	if len(s.ReadOnly) > 0 && !s.hasNamespace("mount") {
+		err = fmt.Errorf("sandbox read only path require mount namespace")
+		return
+	}
+	err = checkShimSupported("sandbox")
+	return
+}
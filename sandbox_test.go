@@ -0,0 +1,71 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSandboxCheck(t *testing.T) {
+	for _, sandbox := range []*Sandbox{
+		{Umask: "999"},
+		{Umask: "1777"},
+		{Namespaces: []string{"user"}},
+		{Chroot: "xx"},
+		{Chroot: "/not-exists-xx"},
+		{ReadOnly: []string{"/tmp"}},
+		{ReadOnly: []string{"tmp"}, Namespaces: []string{"mount"}},
+	} {
+		if sandbox.check() == nil {
+			t.Errorf("%v", toJSON(sandbox))
+			return
+		}
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	sandbox := &Sandbox{Umask: "027", Chroot: "/", Namespaces: []string{"mount", "ipc"}, ReadOnly: []string{"/tmp"}, NoNewPrivs: true}
+	if err := sandbox.check(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	group := &Group{
+		Name:     "sandbox",
+		Filename: "test-service.json",
+	}
+	service := &Service{
+		Name:    "umask",
+		Path:    "/bin/sh",
+		Args:    []string{"-c", "umask; grep NoNewPrivs /proc/self/status"},
+		Stdout:  "umask.log",
+		Sandbox: &Sandbox{Umask: "027", NoNewPrivs: true},
+	}
+	os.Remove("umask.log")
+	defer os.Remove("umask.log")
+	m := NewManager()
+	err := m.StartService(group, service)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	m.locker.Lock()
+	running := m.running["sandbox/umask"]
+	m.locker.Unlock()
+	if running != nil {
+		running.Waiter.Wait()
+	}
+	data, _ := ioutil.ReadFile("umask.log")
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 || fields[0] != "0027" || fields[2] != "1" {
+		t.Errorf("%v", string(data))
+		return
+	}
+}
@@ -0,0 +1,178 @@
+package serviced
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//OverlapSkip will skip the tick when last run is still running
+	OverlapSkip = "skip"
+	//OverlapQueue will run again after last run is done when tick on running
+	OverlapQueue = "queue"
+)
+
+//Schedule is scheduled service running struct
+type Schedule struct {
+	Group        *Group
+	Service      *Service
+	Next         time.Time
+	LastRun      time.Time
+	LastCode     int
+	LastDuration time.Duration
+	LastErr      error
+	Runs         int
+	Skipped      int
+	cron         cronSchedule
+	running      bool
+	stopped      bool
+	pending      int
+	stop         chan int
+	waiter       sync.WaitGroup
+}
+
+//exitCode will return exit code by process wait error, it will return -1 if process is not exited normally
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+//StartSchedule will start schedule service and run it on each tick
+func (m *Manager) StartSchedule(group *Group, service *Service) (schedule *Schedule, err error) {
+	key := fmt.Sprintf("%v/%v", group.Name, service.Name)
+	cron, err := parseSchedule(service.Schedule)
+	if err != nil {
+		return
+	}
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	if m.schedules[key] != nil {
+		err = fmt.Errorf("%v is scheduled", key)
+		return
+	}
+	schedule = &Schedule{
+		Group:   group,
+		Service: service,
+		Next:    cron.Next(time.Now()),
+		cron:    cron,
+		stop:    make(chan int),
+	}
+	m.schedules[key] = schedule
+	schedule.waiter.Add(1)
+	go m.runSchedule(schedule)
+	return
+}
+
+func (m *Manager) runSchedule(schedule *Schedule) {
+	defer schedule.waiter.Done()
+	for {
+		m.locker.RLock()
+		next := schedule.Next
+		m.locker.RUnlock()
+		if next.IsZero() {
+			log.Warnf("%v/%v schedule is done by not next time", schedule.Group.Name, schedule.Service.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-schedule.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		m.locker.Lock()
+		if schedule.stopped {
+			m.locker.Unlock()
+			return
+		}
+		schedule.Next = schedule.cron.Next(time.Now())
+		if schedule.running {
+			if schedule.Service.Overlap == OverlapQueue {
+				schedule.pending++
+				log.Infof("%v/%v is still running, queue the tick with %v pending", schedule.Group.Name, schedule.Service.Name, schedule.pending)
+			} else {
+				schedule.Skipped++
+				log.Infof("%v/%v is still running, skip the tick", schedule.Group.Name, schedule.Service.Name)
+			}
+			m.locker.Unlock()
+			continue
+		}
+		//the job is marked running with stopped checking, so StopSchedule is always waiting the job which is started
+		schedule.running = true
+		schedule.waiter.Add(1)
+		m.locker.Unlock()
+		go m.runScheduleJob(schedule)
+	}
+}
+
+func (m *Manager) runScheduleJob(schedule *Schedule) {
+	defer schedule.waiter.Done()
+	for {
+		m.locker.Lock()
+		if schedule.stopped {
+			schedule.running = false
+			m.locker.Unlock()
+			return
+		}
+		m.locker.Unlock()
+		startTime := time.Now()
+		log.Infof("%v/%v is running by schedule", schedule.Group.Name, schedule.Service.Name)
+		running, err := m.startService(schedule.Group, schedule.Service, 0)
+		if err == nil {
+			m.locker.RLock()
+			stopped := schedule.stopped
+			m.locker.RUnlock()
+			if stopped {
+				//the schedule is stopped when job is starting, so it is not stopped by StopSchedule
+				m.StopService(schedule.Group.Name, schedule.Service.Name)
+			}
+			running.Waiter.Wait()
+			err = running.Err
+		}
+		m.locker.Lock()
+		schedule.Runs++
+		schedule.LastRun = startTime
+		schedule.LastDuration = time.Since(startTime)
+		schedule.LastErr = err
+		schedule.LastCode = exitCode(err)
+		log.Infof("%v/%v schedule run is done with code %v in %v", schedule.Group.Name, schedule.Service.Name, schedule.LastCode, schedule.LastDuration)
+		if schedule.stopped {
+			schedule.pending = 0
+		}
+		if schedule.pending < 1 {
+			schedule.running = false
+			m.locker.Unlock()
+			break
+		}
+		schedule.pending--
+		m.locker.Unlock()
+	}
+}
+
+//StopSchedule will stop schedule service, the running job will be stopped too
+func (m *Manager) StopSchedule(group, name string) (err error) {
+	key := fmt.Sprintf("%v/%v", group, name)
+	m.locker.Lock()
+	schedule := m.schedules[key]
+	if schedule == nil {
+		err = fmt.Errorf("%v is not scheduled", key)
+		m.locker.Unlock()
+		return
+	}
+	delete(m.schedules, key)
+	schedule.stopped = true
+	close(schedule.stop)
+	m.locker.Unlock()
+	m.StopService(group, name)
+	schedule.waiter.Wait()
+	return
+}
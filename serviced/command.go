@@ -0,0 +1,609 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codingeasygo/serviced"
+)
+
+const (
+	//exitOK is the exit code when command is success
+	exitOK = 0
+	//exitFail is the exit code when command is fail or daemon return error
+	exitFail = 1
+	//exitUsage is the exit code when command or arguments is invalid
+	exitUsage = 2
+)
+
+//options is the global and command flags
+type options struct {
+	Config    string
+	Socket    string
+	Timeout   time.Duration
+	Output    string
+	Template  string
+	Rolling   bool
+	NoNewline bool
+	Dev       bool
+}
+
+//command is the sub command of serviced
+type command struct {
+	Name  string
+	Args  string
+	Short string
+	Long  string
+	Min   int
+	Max   int
+	Flags func(fs *flag.FlagSet, opt *options)
+	Run   func(opt *options, args []string) (err error)
+}
+
+//usageError is returned by command when arguments is invalid
+type usageError struct {
+	error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			Name:  "srv",
+			Args:  "[--dev] [config]",
+			Short: "run the serviced daemon",
+			Long: "Run the serviced daemon in foreground, the configure is loaded from --config or config argument,\n" +
+				"default is serviced.json in the executable directory. The console listen on --socket address or console of configure,\n" +
+				"default is 127.0.0.1 with random port, the address is saved to console.serviced.txt in temp directory.\n" +
+				"With --dev the service is restarted when the watched files is changed.",
+			Max: 1,
+			Flags: func(fs *flag.FlagSet, opt *options) {
+				fs.BoolVar(&opt.Dev, "dev", false, "restart service when watched files is changed")
+			},
+			Run: runSrv,
+		},
+		{
+			Name:  "run",
+			Args:  "<group file>",
+			Short: "run group service in foreground",
+			Long: "Run the group service in foreground without daemon, the output of all service is printed with colorized key prefix.\n" +
+				"Ctrl-C stop all service gracefully, the service is killed when it is not exited in " + serviced.StopTimeout.String() + ".\n" +
+				"It exit with non-zero code when any service is start fail or exited with non-zero code or signal.",
+			Min: 1,
+			Max: 1,
+			Run: func(opt *options, args []string) (err error) {
+				return runForeground(args[0])
+			},
+		},
+		{
+			Name:  "add",
+			Args:  "<group file>",
+			Short: "add group service",
+			Long:  "Add the group service by group configure file, the path is sent to daemon as absolute path.",
+			Min:   1,
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				path, _ := filepath.Abs(args[0])
+				return callConsole(opt, func(c *serviced.Console) error { return c.Add(path) })
+			},
+		},
+		{
+			Name:  "remove",
+			Args:  "<group>",
+			Short: "remove group service",
+			Long:  "Stop all service in group and remove the group from configure.",
+			Min:   1,
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error { return c.Remove(args[0]) })
+			},
+		},
+		{
+			Name:  "start",
+			Args:  "<all|group|group/service>",
+			Short: "start service",
+			Long:  "Start the matched service, group and service name support glob like web/* or */worker-*.",
+			Min:   1,
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error { return c.Start(args[0]) })
+			},
+		},
+		{
+			Name:  "stop",
+			Args:  "<all|group|group/service>",
+			Short: "stop service",
+			Long:  "Stop the matched service, group and service name support glob like web/* or */worker-*.",
+			Min:   1,
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error { return c.Stop(args[0]) })
+			},
+		},
+		{
+			Name:  "restart",
+			Args:  "[--rolling] <all|group|group/service>",
+			Short: "restart service",
+			Long: "Restart the matched service, with --rolling the service and instance is restarted one by one\n" +
+				"and each is waited to be ready before moving on.",
+			Min: 1,
+			Max: 1,
+			Flags: func(fs *flag.FlagSet, opt *options) {
+				fs.BoolVar(&opt.Rolling, "rolling", false, "restart one by one and wait each to be ready")
+			},
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error {
+					if opt.Rolling {
+						return c.RollingRestart(args[0])
+					}
+					return c.Restart(args[0])
+				})
+			},
+		},
+		{
+			Name:  "list",
+			Args:  "[-o table|wide|json|yaml] [--template <go template>] [all|group|group/service]",
+			Short: "list service status",
+			Long: "List the matched service status, default target is all. The template is executed on each\n" +
+				"service status like {{.Key}} {{.State}} {{.Pid}}.",
+			Max:   1,
+			Flags: outputFlags,
+			Run: func(opt *options, args []string) (err error) {
+				target := "all"
+				if len(args) > 0 {
+					target = args[0]
+				}
+				return callConsole(opt, func(c *serviced.Console) error { return c.Status(target, opt.Output, opt.Template) })
+			},
+		},
+		{
+			Name:  "history",
+			Args:  "[-o table|wide|json|yaml] [--template <go template>] <all|group|group/service>",
+			Short: "show service exit history",
+			Long: "Show the exit history of matched service with start/stop time, exit code or signal, whether it is stopped\n" +
+				"by serviced, the wide output shows the last stderr line and json/yaml output shows the kept stderr lines.",
+			Min:   1,
+			Max:   1,
+			Flags: outputFlags,
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error { return c.History(args[0], opt.Output, opt.Template) })
+			},
+		},
+		{
+			Name:  "scale",
+			Args:  "<group/service> <count>",
+			Short: "scale service instance",
+			Long:  "Change the instance count of service, the extra instance is stopped from the highest index.",
+			Min:   2,
+			Max:   2,
+			Run: func(opt *options, args []string) (err error) {
+				count, err := strconv.Atoi(args[1])
+				if err != nil {
+					return usageError{fmt.Errorf("invalid count %v", args[1])}
+				}
+				return callConsole(opt, func(c *serviced.Console) error { return c.Scale(args[0], count) })
+			},
+		},
+		{
+			Name:  "signal",
+			Args:  "<all|group|group/service> <signal>",
+			Short: "send signal to service",
+			Long:  "Send signal like SIGHUP, HUP or 1 to the matched running service, only SIGKILL is supported on windows.",
+			Min:   2,
+			Max:   2,
+			Run: func(opt *options, args []string) (err error) {
+				if _, err = serviced.ParseSignal(args[1]); err != nil {
+					return usageError{err}
+				}
+				return callConsole(opt, func(c *serviced.Console) error { return c.Signal(args[0], args[1]) })
+			},
+		},
+		{
+			Name:  "reload-service",
+			Args:  "<all|group|group/service>",
+			Short: "send reload signal to service",
+			Long:  "Send the reload_signal of service to the matched running service, default is SIGHUP.",
+			Min:   1,
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				return callConsole(opt, func(c *serviced.Console) error { return c.ReloadService(args[0]) })
+			},
+		},
+		{
+			Name:  "send",
+			Args:  "[-n] <group/service> <data|->",
+			Short: "write data to service stdin",
+			Long: "Write data to stdin of the matched running service, the service stdin must be configured as pipe.\n" +
+				"The data is read from stdin when it is -, the newline is appended when -n is not set.",
+			Min: 2,
+			Max: 2,
+			Flags: func(fs *flag.FlagSet, opt *options) {
+				fs.BoolVar(&opt.NoNewline, "n", false, "do not append newline")
+			},
+			Run: func(opt *options, args []string) (err error) {
+				data := args[1]
+				if data == "-" {
+					var buffer []byte
+					buffer, err = ioutil.ReadAll(os.Stdin)
+					if err != nil {
+						return
+					}
+					data = string(buffer)
+				} else if !opt.NoNewline {
+					data += "\n"
+				}
+				return callConsole(opt, func(c *serviced.Console) error { return c.Send(args[0], data) })
+			},
+		},
+		{
+			Name:  "attach",
+			Args:  "<group/service>",
+			Short: "attach to service output and stdin",
+			Long: "Stream the live stdout/stderr of running service and forward stdin to it, the input is dropped when\n" +
+				"service stdin is not pipe or tty. The terminal is in raw mode on tty service, press Ctrl-] to detach,\n" +
+				"otherwise detach by Ctrl-C or end of input. The service is not stopped on detaching.",
+			Min: 1,
+			Max: 1,
+			Run: runAttach,
+		},
+		{
+			Name:  "wait",
+			Args:  "<group|group/service> <running|ready|stopped|exited> [timeout]",
+			Short: "wait service to reach state",
+			Long: "Block until all matched service reach the state, exited means exited by itself not stopped by serviced.\n" +
+				"The timeout argument or --timeout is the max waiting time, default is waiting forever.",
+			Min: 2,
+			Max: 3,
+			Run: func(opt *options, args []string) (err error) {
+				timeout := opt.Timeout
+				if len(args) > 2 {
+					timeout, err = time.ParseDuration(args[2])
+					if err != nil {
+						return usageError{fmt.Errorf("invalid timeout %v", args[2])}
+					}
+				}
+				//the timeout is applied by daemon
+				opt.Timeout = 0
+				return callConsole(opt, func(c *serviced.Console) error { return c.Wait(args[0], args[1], timeout) })
+			},
+		},
+		{
+			Name:  "completion",
+			Args:  "<bash|zsh|fish>",
+			Short: "generate shell completion script",
+			Long: "Print the shell completion script, load it by\n" +
+				"\tbash: source <(serviced completion bash)\n" +
+				"\tzsh:  source <(serviced completion zsh)\n" +
+				"\tfish: serviced completion fish | source",
+			Min: 1,
+			Max: 1,
+			Run: func(opt *options, args []string) (err error) {
+				return writeCompletion(os.Stdout, args[0])
+			},
+		},
+		{
+			Name:  "help",
+			Args:  "[command]",
+			Short: "show help of command",
+			Max:   1,
+			Run: func(opt *options, args []string) (err error) {
+				if len(args) < 1 {
+					printUsage(os.Stdout)
+					return
+				}
+				cmd := findCommand(args[0])
+				if cmd == nil {
+					return usageError{fmt.Errorf("unknown command %v", args[0])}
+				}
+				cmd.help(os.Stdout)
+				return
+			},
+		},
+	}
+	commands = append(commands, platformCommands...)
+}
+
+//findCommand will return command by name, it return nil when not found
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+//globalFlags will add the flags which is supported by all command
+func globalFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.Config, "config", opt.Config, "the daemon configure file")
+	fs.StringVar(&opt.Socket, "socket", opt.Socket, "the console address, default is read from console of --config or console.serviced.txt")
+	fs.DurationVar(&opt.Timeout, "timeout", opt.Timeout, "the max time to wait command result, 0 is forever")
+}
+
+//outputFlags will add the flags to control the output format
+func outputFlags(fs *flag.FlagSet, opt *options) {
+	fs.StringVar(&opt.Output, "output", opt.Output, "the output format by table|wide|json|yaml")
+	fs.StringVar(&opt.Output, "o", opt.Output, "shorthand for --output")
+	fs.StringVar(&opt.Template, "template", opt.Template, "the go template executed on each item")
+}
+
+//flagSet will create the flag set of command
+func (c *command) flagSet(opt *options) (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("serviced "+c.Name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	globalFlags(fs, opt)
+	if c.Flags != nil {
+		c.Flags(fs, opt)
+	}
+	return
+}
+
+//help will print the command help
+func (c *command) help(out io.Writer) {
+	fmt.Fprintf(out, "Usage: serviced %v %v\n\n", c.Name, c.Args)
+	if len(c.Long) > 0 {
+		fmt.Fprintf(out, "%v\n\n", c.Long)
+	} else {
+		fmt.Fprintf(out, "%v\n\n", c.Short)
+	}
+	fmt.Fprintf(out, "Flags:\n")
+	fs := c.flagSet(&options{})
+	fs.SetOutput(out)
+	fs.PrintDefaults()
+}
+
+//printUsage will print the usage of all command
+func printUsage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: serviced [--config file] [--socket address] [--timeout duration] <command> [arguments]\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "\t%-12v %v\n", cmd.Name, cmd.Short)
+	}
+	fmt.Fprintf(out, "\nUse \"serviced help <command>\" for more information about a command.\n")
+}
+
+//parseArgs will parse flags which is mixed with arguments, the arguments after -- is not parsed
+func parseArgs(fs *flag.FlagSet, args []string) (positional []string, err error) {
+	for {
+		err = fs.Parse(args)
+		if err != nil {
+			return
+		}
+		rest := fs.Args()
+		if len(rest) < 1 {
+			return
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			return
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+//runCommand will run command by arguments and return the exit code
+func runCommand(args []string) (code int) {
+	opt := &options{}
+	global := flag.NewFlagSet("serviced", flag.ContinueOnError)
+	global.SetOutput(ioutil.Discard)
+	globalFlags(global, opt)
+	err := global.Parse(args)
+	if err == flag.ErrHelp {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	if err != nil || global.NArg() < 1 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "serviced: %v\n", err)
+		}
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	args = global.Args()
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "serviced: unknown command %v\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	args, err = parseArgs(cmd.flagSet(opt), args[1:])
+	if err == flag.ErrHelp {
+		cmd.help(os.Stdout)
+		return exitOK
+	}
+	if err == nil && (len(args) < cmd.Min || len(args) > cmd.Max) {
+		err = fmt.Errorf("accepts %v to %v arguments, received %v", cmd.Min, cmd.Max, len(args))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "serviced %v: %v\nUsage: serviced %v %v\n", cmd.Name, err, cmd.Name, cmd.Args)
+		return exitUsage
+	}
+	err = cmd.Run(opt, args)
+	if _, ok := err.(usageError); ok {
+		fmt.Fprintf(os.Stderr, "serviced %v: %v\nUsage: serviced %v %v\n", cmd.Name, err, cmd.Name, cmd.Args)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFail
+	}
+	return exitOK
+}
+
+//callConsole will connect to daemon console and call it
+func callConsole(opt *options, call func(c *serviced.Console) error) (err error) {
+	c, err := dialConsole(opt)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	go c.CopyTo(os.Stdout)
+	err = call(c)
+	return
+}
+
+//dialConsole will connect to daemon console by --socket, console of --config or console.serviced.txt
+func dialConsole(opt *options) (c *serviced.Console, err error) {
+	c = serviced.NewConsole()
+	c.Timeout = opt.Timeout
+	c.Filename = opt.Config
+	switch runtime.GOOS {
+	case "windows":
+		path, _ := exePath()
+		c.TempDir = filepath.Dir(path)
+	default:
+		c.TempDir = os.TempDir()
+	}
+	if len(opt.Socket) > 0 {
+		err = c.Dial(opt.Socket)
+	} else {
+		err = c.Bootstrap()
+	}
+	return
+}
+
+//detachKey is the Ctrl-] to detach on raw terminal
+const detachKey = 0x1d
+
+//detachReader will return EOF when detach key is read
+type detachReader struct {
+	io.Reader
+}
+
+func (d *detachReader) Read(p []byte) (n int, err error) {
+	n, err = d.Reader.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == detachKey {
+			n, err = i, io.EOF
+			break
+		}
+	}
+	return
+}
+
+func runAttach(opt *options, args []string) (err error) {
+	c, err := dialConsole(opt)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	var restore func()
+	err = c.Attach(args[0], &detachReader{Reader: os.Stdin}, os.Stdout, func(tty bool) {
+		if !tty {
+			fmt.Fprintf(os.Stderr, "attached to %v\n", args[0])
+			return
+		}
+		var rawErr error
+		restore, rawErr = makeRaw(int(os.Stdin.Fd()))
+		if rawErr == nil {
+			fmt.Fprintf(os.Stderr, "attached to %v, press Ctrl-] to detach\r\n", args[0])
+		} else {
+			fmt.Fprintf(os.Stderr, "attached to %v\n", args[0])
+		}
+	})
+	if restore != nil {
+		restore()
+	}
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "\ndetached from %v\n", args[0])
+	}
+	return
+}
+
+func runSrv(opt *options, args []string) (err error) {
+	conf := opt.Config
+	if len(args) > 0 {
+		conf = args[0]
+	}
+	runService(conf, opt.Socket, opt.Dev)
+	return
+}
+
+//writeCompletion will write the completion script of shell
+func writeCompletion(out io.Writer, shell string) (err error) {
+	names := []string{}
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+	}
+	targets := "start stop restart list history scale signal reload-service send attach wait remove"
+	switch shell {
+	case "bash", "zsh":
+		if shell == "zsh" {
+			fmt.Fprintf(out, "autoload -U +X bashcompinit && bashcompinit\n")
+		}
+		fmt.Fprintf(out, bashCompletion, strings.Join(names, " "), strings.ReplaceAll(targets, " ", "|"))
+	case "fish":
+		fmt.Fprintf(out, fishCompletion, strings.Join(names, " "), targets)
+	default:
+		err = usageError{fmt.Errorf("not supported shell %v", shell)}
+	}
+	return
+}
+
+const bashCompletion = `_serviced() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+	-o|--output)
+		COMPREPLY=( $(compgen -W "table wide json yaml" -- "$cur") )
+		return
+		;;
+	--config)
+		COMPREPLY=( $(compgen -f -- "$cur") )
+		return
+		;;
+	--socket|--timeout|--template)
+		return
+		;;
+	esac
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=( $(compgen -W "%v" -- "$cur") )
+		return
+	fi
+	case "$cur" in
+	-*)
+		COMPREPLY=( $(compgen -W "--config --socket --timeout --output --template --rolling --dev --help" -- "$cur") )
+		return
+		;;
+	esac
+	case "${COMP_WORDS[1]}" in
+	add|srv|run)
+		COMPREPLY=( $(compgen -f -- "$cur") )
+		;;
+	completion)
+		COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") )
+		;;
+	help)
+		COMPREPLY=( $(compgen -W "%[1]v" -- "$cur") )
+		;;
+	%v)
+		COMPREPLY=( $(compgen -W "all $(serviced list --timeout 3s --template '{{.Group}} {{.Group}}/{{.Name}}' all 2>/dev/null | sort -u)" -- "$cur") )
+		;;
+	esac
+}
+complete -F _serviced serviced
+`
+
+const fishCompletion = `complete -c serviced -f
+complete -c serviced -n '__fish_use_subcommand' -a '%v'
+complete -c serviced -n '__fish_seen_subcommand_from add srv run' -F
+complete -c serviced -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+complete -c serviced -n '__fish_seen_subcommand_from %v' -a 'all (serviced list --timeout 3s --template "{{.Group}} {{.Group}}/{{.Name}}" all 2>/dev/null | string split " " | sort -u)'
+complete -c serviced -l config -r -F -d 'the daemon configure file'
+complete -c serviced -l socket -x -d 'the console address'
+complete -c serviced -l timeout -x -d 'the max time to wait command result'
+complete -c serviced -s o -l output -x -a 'table wide json yaml' -d 'the output format'
+complete -c serviced -l template -x -d 'the go template executed on each item'
+complete -c serviced -l rolling -d 'restart one by one'
+complete -c serviced -l dev -d 'restart service when watched files is changed'
+`
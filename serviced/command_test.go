@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codingeasygo/serviced"
+)
+
+func TestParseArgs(t *testing.T) {
+	opt := &options{}
+	cmd := findCommand("list")
+	args, err := parseArgs(cmd.flagSet(opt), []string{"web", "-o", "json", "--timeout", "3s", "--", "--template"})
+	if err != nil || len(args) != 2 || args[0] != "web" || args[1] != "--template" || opt.Output != "json" || opt.Timeout.String() != "3s" {
+		t.Errorf("%v,%v,%v", err, args, opt)
+		return
+	}
+	_, err = parseArgs(cmd.flagSet(&options{}), []string{"web", "--xx"})
+	if err == nil {
+		t.Error("error")
+		return
+	}
+	_, err = parseArgs(cmd.flagSet(&options{}), []string{"--help"})
+	if err != flag.ErrHelp {
+		t.Error(err)
+		return
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "serviced")
+	defer os.RemoveAll(dir)
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	console := listener.Addr().String()
+	listener.Close()
+	conf := filepath.Join(dir, "serviced.json")
+	ioutil.WriteFile(conf, []byte(fmt.Sprintf(`{"console":"%v","includes":{}}`, console)), 0644)
+	m := serviced.NewManager()
+	m.Filename = conf
+	m.TempDir = dir
+	err := m.Bootstrap()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer m.StopConsole()
+	missing := filepath.Join(dir, "missing.json")
+	for _, c := range []struct {
+		Args []string
+		Code int
+	}{
+		//usage
+		{Args: []string{}, Code: exitUsage},
+		{Args: []string{"--xx"}, Code: exitUsage},
+		{Args: []string{"xx"}, Code: exitUsage},
+		{Args: []string{"start"}, Code: exitUsage},
+		{Args: []string{"start", "a", "b"}, Code: exitUsage},
+		{Args: []string{"wait", "web"}, Code: exitUsage},
+		{Args: []string{"wait", "web", "running", "xx"}, Code: exitUsage},
+		{Args: []string{"list", "--xx"}, Code: exitUsage},
+		{Args: []string{"help", "xx"}, Code: exitUsage},
+		{Args: []string{"completion", "xx"}, Code: exitUsage},
+		//help
+		{Args: []string{"--help"}, Code: exitOK},
+		{Args: []string{"start", "--help"}, Code: exitOK},
+		{Args: []string{"help", "start"}, Code: exitOK},
+		//console by --config
+		{Args: []string{"--config", conf, "list", "all"}, Code: exitOK},
+		{Args: []string{"list", "all", "--config", conf}, Code: exitOK},
+		{Args: []string{"--config", conf, "start", "none"}, Code: exitFail},
+		{Args: []string{"--config", missing, "list", "all"}, Code: exitFail},
+		{Args: []string{"--socket", console, "list", "all"}, Code: exitOK},
+	} {
+		if code := runCommand(c.Args); code != c.Code {
+			t.Errorf("%v: %v", c.Args, code)
+			return
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		buffer := bytes.NewBuffer(nil)
+		err := writeCompletion(buffer, shell)
+		if err != nil || !strings.Contains(buffer.String(), "reload-service") || !strings.Contains(buffer.String(), "config") {
+			t.Errorf("%v,%v", err, buffer.String())
+			return
+		}
+	}
+	if err := writeCompletion(ioutil.Discard, "xx"); err == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	findCommand("run").help(buffer)
+	if !strings.Contains(buffer.String(), "not exited in "+serviced.StopTimeout.String()) {
+		t.Error(buffer.String())
+		return
+	}
+}
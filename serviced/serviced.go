@@ -15,24 +15,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func usage() {
-	switch runtime.GOOS {
-	case "windows":
-		fmt.Printf("Usage: serviced <install|uninstall|stat|stop|list|add|remove>\n")
-		fmt.Printf("\tinstall\t\t install windows service\n")
-		fmt.Printf("\tuninstall\t\t remove windows service\n")
-	default:
-		fmt.Printf("Usage: serviced <srv|stat|stop|list|add|remove>\n")
-	}
-	fmt.Printf("\tstart\t\t start group service\n")
-	fmt.Printf("\tstop\t\t stop group service\n")
-	fmt.Printf("\tlist\t\t list group service\n")
-	fmt.Printf("\tadd\t\t add group service\n")
-	fmt.Printf("\tremove\t\t remove group service\n")
-	fmt.Printf("\n")
-}
-
 func main() {
+	serviced.RunShim()
 	_, name := filepath.Split(os.Args[0])
 	name = strings.TrimSuffix(name, ".exe")
 	switch name {
@@ -42,7 +26,7 @@ func main() {
 			if len(os.Args) > 2 {
 				conf = os.Args[2]
 			}
-			runService(conf)
+			runService(conf, "", false)
 			return
 		}
 		switch runtime.GOOS {
@@ -53,20 +37,22 @@ func main() {
 			if len(os.Args) > 1 {
 				conf = os.Args[1]
 			}
-			runService(conf)
+			runService(conf, "", false)
 		}
 	default:
-		runConsole()
+		os.Exit(runCommand(os.Args[1:]))
 	}
 }
 
 var service *serviced.Manager
 
-func runService(conf string) {
+func runService(conf, consoleAddr string, dev bool) {
 	log.SetFormatter(NewPlainFormatter())
 	path, _ := exePath()
 	dir := filepath.Dir(path)
 	service = serviced.NewManager()
+	service.ConsoleAddr = consoleAddr
+	service.Dev = dev
 	if len(conf) > 0 {
 		service.Filename = conf
 	} else {
@@ -94,49 +80,33 @@ func runService(conf string) {
 	stopService()
 }
 
-func stopService() {
-	service.StopAll()
-	service.StopConsole()
-}
-
-func runConsole() {
-	if len(os.Args) < 3 {
-		usage()
-		return
-	}
-	path, _ := exePath()
-	dir := filepath.Dir(path)
-	c := serviced.NewConsole()
-	switch runtime.GOOS {
-	case "windows":
-		c.TempDir = dir
-	default:
-		c.TempDir = os.TempDir()
-	}
-	err := c.Bootstrap()
+//runForeground will run the group file in foreground without console and daemon configure
+func runForeground(filename string) (err error) {
+	log.SetFormatter(NewPlainFormatter())
+	service = serviced.NewManager()
+	service.Foreground = true
+	service.Output = os.Stdout
+	service.Color = isTerminal(int(os.Stdout.Fd())) && len(os.Getenv("NO_COLOR")) < 1
+	_, err = service.LoadGroup(filename)
 	if err != nil {
-		fmt.Printf("%v\n", err)
-		os.Exit(1)
 		return
 	}
-	go c.CopyTo(os.Stdout)
-	defer c.Close()
-	switch os.Args[1] {
-	case "add":
-		path, _ := filepath.Abs(os.Args[2])
-		c.Add(path)
-	case "remove":
-		c.Remove(os.Args[2])
-	case "start":
-		c.Start(os.Args[2])
-	case "stop":
-		c.Stop(os.Args[2])
-	case "list":
-		c.List(os.Args[2])
-	default:
-		usage()
-		os.Exit(1)
-	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop,
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGTERM)
+	defer signal.Stop(stop)
+	err = service.Run(stop)
+	return
+}
+
+func stopService() {
+	service.StopAll()
+	service.CloseSockets("*")
+	service.CloseProxy("*")
+	service.StopConsole()
+	service.StopLog()
 }
 
 func exePath() (string, error) {
@@ -185,6 +155,6 @@ func NewPlainFormatter() (formatter *PlainFormatter) {
 
 //Format will format the log entry
 func (f *PlainFormatter) Format(entry *log.Entry) ([]byte, error) {
-	timestamp := fmt.Sprintf(entry.Time.Format(f.TimestampFormat))
+	timestamp := entry.Time.Format(f.TimestampFormat)
 	return []byte(fmt.Sprintf("%s %s %s\n", timestamp, f.LevelDesc[entry.Level], entry.Message)), nil
 }
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+var platformCommands = []*command{}
+
+//windowService is only used on windows, it run service directly on other os
+func windowService() {
+	runService("", "", false)
+}
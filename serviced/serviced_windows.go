@@ -33,21 +33,30 @@ func windowService() {
 		runWinService("serviced", false)
 		return
 	}
-	if len(os.Args) < 2 {
-		usage()
-		os.Exit(1)
-	}
-	switch os.Args[1] {
-	case "install":
-		err = installService("serviced", "Serviced")
-		fmt.Printf("install serviced done with %v\n", err)
-	case "uninstall":
-		err = removeService("serviced")
-		fmt.Printf("remove serviced done with %v\n", err)
-	default:
-		runConsole()
-	}
+	os.Exit(runCommand(os.Args[1:]))
+}
 
+var platformCommands = []*command{
+	{
+		Name:  "install",
+		Short: "install windows service",
+		Long:  "Install serviced as windows service which is started automatically.",
+		Run: func(opt *options, args []string) (err error) {
+			err = installService("serviced", "Serviced")
+			fmt.Printf("install serviced done with %v\n", err)
+			return
+		},
+	},
+	{
+		Name:  "uninstall",
+		Short: "remove windows service",
+		Long:  "Remove the serviced windows service.",
+		Run: func(opt *options, args []string) (err error) {
+			err = removeService("serviced")
+			fmt.Printf("remove serviced done with %v\n", err)
+			return
+		},
+	},
 }
 
 var elog debug.Log
@@ -57,7 +66,7 @@ type myservice struct{}
 func (m *myservice) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
 	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown
 	changes <- svc.Status{State: svc.StartPending}
-	go runService("")
+	go runService("", "", false)
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
 	for {
 		c := <-r
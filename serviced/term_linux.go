@@ -0,0 +1,35 @@
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+//makeRaw will put the terminal in raw mode, it fail when fd is not terminal, the restore must be called after using
+func makeRaw(fd int) (restore func(), err error) {
+	old, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return
+	}
+	raw := *old
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, &raw)
+	if err != nil {
+		return
+	}
+	restore = func() {
+		unix.IoctlSetTermios(fd, unix.TCSETS, old)
+	}
+	return
+}
+
+//isTerminal will return true if fd is terminal
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	return err == nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+//makeRaw will return error because raw terminal is only supported on linux
+func makeRaw(fd int) (restore func(), err error) {
+	err = fmt.Errorf("raw terminal is not supported on %v", runtime.GOOS)
+	return
+}
+
+//isTerminal will return false because terminal is only detected on linux
+func isTerminal(fd int) bool {
+	return false
+}
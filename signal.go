@@ -0,0 +1,73 @@
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//DefaultReloadSignal is the default signal sent by reload
+const DefaultReloadSignal = "SIGHUP"
+
+//Signal will send signal to running service matched by pattern, pattern is all, group name or group/service with glob like web/* or */worker-*
+func (m *Manager) Signal(pattern string, sig os.Signal) (err error) {
+	err = m.signalMatched(pattern, func(running *Running) (os.Signal, error) { return sig, nil })
+	return
+}
+
+//ReloadService will send reload_signal of service to running service matched by pattern, default is SIGHUP
+func (m *Manager) ReloadService(pattern string) (err error) {
+	err = m.signalMatched(pattern, func(running *Running) (os.Signal, error) { return running.Service.reloadSignal() })
+	return
+}
+
+func (m *Manager) signalMatched(pattern string, signal func(running *Running) (os.Signal, error)) (err error) {
+	signaling := []*Running{}
+	m.locker.RLock()
+	for key, running := range m.running {
+		if matchTarget(pattern, running.Group.Name, key) {
+			signaling = append(signaling, running)
+		}
+	}
+	m.locker.RUnlock()
+	if len(signaling) < 1 {
+		err = fmt.Errorf("service %v is not running", pattern)
+		return
+	}
+	for _, running := range signaling {
+		sig, sigErr := signal(running)
+		if sigErr == nil {
+			log.Infof("%v is signaled by %v", running.Key, sig)
+			sigErr = running.Cmd.Process.Signal(sig)
+		}
+		if sigErr != nil && err == nil {
+			err = fmt.Errorf("signal %v fail with %v", running.Key, sigErr)
+		}
+	}
+	return
+}
+
+//reloadSignal will return the reload_signal of service, default is SIGHUP
+func (s *Service) reloadSignal() (sig os.Signal, err error) {
+	name := s.ReloadSignal
+	if len(name) < 1 {
+		name = DefaultReloadSignal
+	}
+	sig, err = ParseSignal(name)
+	return
+}
+
+//ParseSignal will parse signal by name like SIGHUP, HUP or number
+func ParseSignal(name string) (sig os.Signal, err error) {
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	sig, err = parseSignal(upper)
+	if err != nil {
+		err = fmt.Errorf("signal %v is not supported", name)
+	}
+	return
+}
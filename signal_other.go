@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package serviced
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+//parseSignal will parse signal by name like SIGHUP or SIG1
+func parseSignal(name string) (sig os.Signal, err error) {
+	if num, e := strconv.Atoi(name[3:]); e == nil && num > 0 && num < 65 {
+		sig = syscall.Signal(num)
+		return
+	}
+	if num := unix.SignalNum(name); num > 0 {
+		sig = num
+		return
+	}
+	err = fmt.Errorf("unknown signal %v", name)
+	return
+}
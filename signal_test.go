@@ -0,0 +1,67 @@
+//go:build !windows
+// +build !windows
+
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignal(t *testing.T) {
+	defer os.Remove("test-signal.log")
+	m := NewManager()
+	m.init()
+	m.Groups["signal"] = Group{
+		Name:     "signal",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "trap", Path: "/bin/sh", Args: []string{"-c", "trap 'echo hup' HUP; trap 'echo usr1' USR1; while true; do sleep 0.05; done"}, Stdout: "test-signal.log", ReloadSignal: "USR1"},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "signal")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	err = m.Signal("signal", syscall.SIGHUP)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	err = m.ReloadService("signal/trap")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	if data, _ := ioutil.ReadFile("test-signal.log"); string(data) != "hup\nusr1\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	if m.Signal("none", syscall.SIGHUP) == nil {
+		t.Error("error")
+		return
+	}
+	//parse
+	for name, sig := range map[string]os.Signal{"SIGHUP": syscall.SIGHUP, "hup": syscall.SIGHUP, "Usr1": syscall.SIGUSR1, "9": syscall.SIGKILL} {
+		if parsed, err := ParseSignal(name); err != nil || parsed != sig {
+			t.Errorf("%v,%v,%v", name, parsed, err)
+			return
+		}
+	}
+	if _, err := ParseSignal("xx"); err == nil {
+		t.Error("error")
+		return
+	}
+	if (&Service{Name: "a", Path: "a", ReloadSignal: "xx"}).check() == nil {
+		t.Error("error")
+		return
+	}
+}
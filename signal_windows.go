@@ -0,0 +1,17 @@
+package serviced
+
+import (
+	"fmt"
+	"os"
+)
+
+//parseSignal will parse signal by name, only SIGKILL is supported on windows
+func parseSignal(name string) (sig os.Signal, err error) {
+	switch name {
+	case "SIGKILL", "SIG9":
+		sig = os.Kill
+	default:
+		err = fmt.Errorf("unknown signal %v", name)
+	}
+	return
+}
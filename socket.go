@@ -0,0 +1,157 @@
+package serviced
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//serviceSockets is the listener bound by manager and passed to service, it is kept open across service restarts
+type serviceSockets struct {
+	Addrs     []string
+	listeners []net.Listener
+	files     []*os.File
+}
+
+//parseSocket will parse socket address like tcp://127.0.0.1:8080, tcp6://[::1]:8080 or unix:///run/web.sock
+func parseSocket(addr string) (network, address string, err error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || len(parts[1]) < 1 {
+		err = fmt.Errorf("socket %v is invalid, it must be like tcp://127.0.0.1:8080 or unix:///run/web.sock", addr)
+		return
+	}
+	network, address = parts[0], parts[1]
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		err = fmt.Errorf("socket %v network must be tcp, tcp4, tcp6 or unix", addr)
+	}
+	return
+}
+
+//removeStaleSocket will remove the unix socket file which is not listened by any process,
+//it return error when the socket is still accepting connection
+func removeStaleSocket(address string) (err error) {
+	info, statErr := os.Lstat(address)
+	if statErr != nil || info.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	conn, dialErr := net.DialTimeout("unix", address, time.Second)
+	if dialErr == nil {
+		conn.Close()
+		err = fmt.Errorf("unix socket %v is in use by other process", address)
+		return
+	}
+	if errors.Is(dialErr, syscall.ECONNREFUSED) {
+		os.Remove(address)
+	}
+	return
+}
+
+//openSockets will bind all address, the stale unix socket file which is refusing connection is removed before binding
+func openSockets(addrs []string) (sockets *serviceSockets, err error) {
+	sockets = &serviceSockets{Addrs: addrs}
+	for _, addr := range addrs {
+		var network, address string
+		network, address, err = parseSocket(addr)
+		if err != nil {
+			break
+		}
+		if network == "unix" {
+			err = removeStaleSocket(address)
+			if err != nil {
+				break
+			}
+		}
+		var listener net.Listener
+		listener, err = net.Listen(network, address)
+		if err != nil {
+			err = fmt.Errorf("listen socket %v fail with %v", addr, err)
+			break
+		}
+		sockets.listeners = append(sockets.listeners, listener)
+		var file *os.File
+		switch l := listener.(type) {
+		case *net.TCPListener:
+			file, err = l.File()
+		case *net.UnixListener:
+			file, err = l.File()
+		}
+		if err != nil {
+			err = fmt.Errorf("dup socket %v fail with %v", addr, err)
+			break
+		}
+		sockets.files = append(sockets.files, file)
+	}
+	if err != nil {
+		sockets.Close()
+		sockets = nil
+	}
+	return
+}
+
+//same will return true if sockets is bound by addrs
+func (s *serviceSockets) same(addrs []string) bool {
+	if len(s.Addrs) != len(addrs) {
+		return false
+	}
+	for i, addr := range addrs {
+		if s.Addrs[i] != addr {
+			return false
+		}
+	}
+	return true
+}
+
+//Close will close all listener
+func (s *serviceSockets) Close() {
+	for _, file := range s.files {
+		file.Close()
+	}
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+}
+
+//bindSockets will return the socket file of service by group/service key, the sockets is bound on first call and reused by all instance and restarts,
+//it is rebound when address is changed
+func (m *Manager) bindSockets(key string, addrs []string) (files []*os.File, err error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	sockets := m.sockets[key]
+	if sockets != nil && !sockets.same(addrs) {
+		log.Infof("%v sockets is changed from %v to %v", key, sockets.Addrs, addrs)
+		sockets.Close()
+		delete(m.sockets, key)
+		sockets = nil
+	}
+	if sockets == nil {
+		sockets, err = openSockets(addrs)
+		if err != nil {
+			return
+		}
+		log.Infof("%v sockets %v is bound", key, addrs)
+		m.sockets[key] = sockets
+	}
+	files = sockets.files
+	return
+}
+
+//CloseSockets will close the sockets of service in group, group * is all
+func (m *Manager) CloseSockets(group string) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for key, sockets := range m.sockets {
+		if group == "*" || strings.HasPrefix(key, group+"/") {
+			log.Infof("%v sockets %v is closed", key, sockets.Addrs)
+			sockets.Close()
+			delete(m.sockets, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSockets(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	defer os.Remove("test-socket.log")
+	defer os.Remove("test-socket.sock")
+	m := NewManager()
+	m.init()
+	m.Groups["socket"] = Group{
+		Name:     "socket",
+		Filename: "test-service.json",
+		Services: []Service{
+			{
+				Name:    "web",
+				Path:    "/bin/sh",
+				Args:    []string{"-c", `[ "$LISTEN_PID" = "$$" ] && echo $LISTEN_FDS $(readlink /proc/$$/fd/3 | cut -c1-7) $(readlink /proc/$$/fd/4 | cut -c1-7); exec sleep 3`},
+				Stdout:  "test-socket.log",
+				Sockets: []string{"tcp://127.0.0.1:0", "unix://test-socket.sock"},
+			},
+		},
+	}
+	defer m.CloseSockets("*")
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "socket")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	if data, _ := ioutil.ReadFile("test-socket.log"); string(data) != "2 socket: socket:\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	addr := m.sockets["socket/web"].listeners[0].Addr().String()
+	//keep listening on restart
+	err = m.Restart(ioutil.Discard, "socket/web")
+	if err != nil || m.sockets["socket/web"].listeners[0].Addr().String() != addr {
+		t.Errorf("%v", err)
+		return
+	}
+	m.StopAll()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conn.Close()
+	m.CloseSockets("socket")
+	if _, err = net.Dial("tcp", addr); err == nil || len(m.sockets) > 0 {
+		t.Error("error")
+		return
+	}
+	//unix socket in use is not removed, the stale socket is removed
+	defer os.Remove("test-stale.sock")
+	listener, err := net.Listen("unix", "test-stale.sock")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = openSockets([]string{"unix://test-stale.sock"}); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Error(err)
+		return
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	sockets, err := openSockets([]string{"unix://test-stale.sock"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sockets.Close()
+	ioutil.WriteFile("test-stale.sock", []byte("data"), 0644)
+	if _, err = openSockets([]string{"unix://test-stale.sock"}); err == nil {
+		t.Error("error")
+		return
+	}
+	if data, _ := ioutil.ReadFile("test-stale.sock"); string(data) != "data" {
+		t.Error("error")
+		return
+	}
+	//check
+	for _, addr := range []string{"xx", "udp://127.0.0.1:80", "tcp://"} {
+		if err := (&Service{Name: "a", Path: "a", Sockets: []string{addr}}).check(); err == nil || !strings.Contains(err.Error(), "socket") {
+			t.Errorf("%v,%v", addr, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package serviced
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	//FormatTable is the output format of compact table
+	FormatTable = "table"
+	//FormatWide is the output format of table with all columns
+	FormatWide = "wide"
+	//FormatJSON is the output format of json
+	FormatJSON = "json"
+	//FormatYAML is the output format of yaml
+	FormatYAML = "yaml"
+)
+
+//Status is the service status
+type Status struct {
+	Key          string         `json:"key" yaml:"key"`
+	Group        string         `json:"group" yaml:"group"`
+	Name         string         `json:"name" yaml:"name"`
+	Instance     int            `json:"instance" yaml:"instance"`
+	State        string         `json:"state" yaml:"state"`
+	Pid          int            `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Path         string         `json:"path" yaml:"path"`
+	Args         []string       `json:"args" yaml:"args"`
+	Dir          string         `json:"dir" yaml:"dir"`
+	Memory       int64          `json:"memory,omitempty" yaml:"memory,omitempty"`
+	Ports        map[string]int `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Next         *time.Time     `json:"next,omitempty" yaml:"next,omitempty"`
+	LastRun      *time.Time     `json:"last_run,omitempty" yaml:"last_run,omitempty"`
+	LastCode     int            `json:"last_code,omitempty" yaml:"last_code,omitempty"`
+	LastDuration string         `json:"last_duration,omitempty" yaml:"last_duration,omitempty"`
+}
+
+//Status will return all service status matched by pattern, pattern is all, group name or group/service with glob, sorted by key
+func (m *Manager) Status(pattern string) (status []*Status) {
+	m.locker.RLock()
+	defer m.locker.RUnlock()
+	for _, g := range m.Groups {
+		for _, service := range g.Services {
+			count := m.scaledCount(&g, &service)
+			for instance := 0; instance < count; instance++ {
+				key := instanceKey(&g, &service, instance)
+				if !matchTarget(pattern, g.Name, key) {
+					continue
+				}
+				s := &Status{
+					Key:      key,
+					Group:    g.Name,
+					Name:     service.Name,
+					Instance: instance,
+					State:    "stopped",
+					Path:     service.Path,
+					Args:     service.Args,
+					Dir:      service.Dir,
+				}
+				if !filepath.IsAbs(s.Dir) {
+					s.Dir = filepath.Join(filepath.Dir(g.Filename), s.Dir)
+				}
+				m.fillStatus(s)
+				status = append(status, s)
+			}
+		}
+	}
+	//the running which is removed from configure
+	for key, running := range m.running {
+		if !matchTarget(pattern, running.Group.Name, key) || m.Find(running.Group.Name) != nil {
+			continue
+		}
+		s := &Status{
+			Key:      key,
+			Group:    running.Group.Name,
+			Name:     running.Service.Name,
+			Instance: running.Instance,
+		}
+		m.fillStatus(s)
+		status = append(status, s)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Key < status[j].Key })
+	return
+}
+
+//fillStatus will fill running/schedule info to status, it must be called with locker
+func (m *Manager) fillStatus(s *Status) {
+	if schedule, ok := m.schedules[s.Group+"/"+s.Name]; ok {
+		s.State = "scheduled"
+		if !schedule.Next.IsZero() {
+			next := schedule.Next
+			s.Next = &next
+		}
+		if !schedule.LastRun.IsZero() {
+			last := schedule.LastRun
+			s.LastRun = &last
+			s.LastCode = schedule.LastCode
+			s.LastDuration = schedule.LastDuration.String()
+		}
+	}
+	if exit := m.lastExit(s.Group, s.Name, s.Key); s.LastRun == nil && exit != nil {
+		last := exit.Start
+		s.LastRun = &last
+		s.LastCode = exit.Code
+		s.LastDuration = exit.Stop.Sub(exit.Start).Round(time.Millisecond).String()
+	}
+	if ports := m.ports[s.Key]; len(ports) > 0 {
+		s.Ports = ports
+	}
+	running, ok := m.running[s.Key]
+	if !ok {
+		return
+	}
+	s.State = "running"
+	if running.State == StateReady {
+		s.State = "ready"
+	}
+	s.Path = running.Cmd.Args[0]
+	s.Args = running.Cmd.Args[1:]
+	s.Dir = running.Cmd.Dir
+	if running.Cmd.Process != nil {
+		s.Pid = running.Cmd.Process.Pid
+	}
+	if len(running.Cgroup) > 0 {
+		s.Memory, _ = cgroupMemory(running.Cgroup)
+	}
+}
+
+//quoteArg will quote the argument if it is empty or having space/special char
+func quoteArg(arg string) string {
+	if len(arg) < 1 || strings.ContainsAny(arg, " \t\r\n\"'\\$`") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+//cell will escape table cell to keep one line
+func cell(val string) string {
+	if len(val) < 1 {
+		return "-"
+	}
+	return strings.NewReplacer("\t", "\\t", "\r", "\\r", "\n", "\\n").Replace(val)
+}
+
+//WriteStatus will write status by format, format is table/wide/json/yaml, the tmpl is go template which is executed on each status if it is not empty,
+//the NEXT/LAST column is shown on table when any service is scheduled
+func WriteStatus(out io.Writer, status []*Status, format, tmpl string) (err error) {
+	scheduled := false
+	for _, s := range status {
+		scheduled = scheduled || s.Next != nil
+	}
+	return writeItems(out, status, format, tmpl, func(writer *tabwriter.Writer, wide bool) {
+		if wide {
+			fmt.Fprintf(writer, "STATE\tNAME\tGROUP\tPID\tMEMORY\tPORTS\tPATH\tARGS\tDIR\tNEXT\tLAST\n")
+		} else if scheduled {
+			fmt.Fprintf(writer, "STATE\tNAME\tGROUP\tPID\tMEMORY\tPORTS\tNEXT\tLAST\n")
+		} else {
+			fmt.Fprintf(writer, "STATE\tNAME\tGROUP\tPID\tMEMORY\tPORTS\n")
+		}
+		for _, s := range status {
+			pid, memory := "-", "-"
+			if s.Pid > 0 {
+				pid = fmt.Sprintf("%v", s.Pid)
+			}
+			if s.Memory > 0 {
+				memory = formatSize(s.Memory)
+			}
+			name := strings.TrimPrefix(s.Key, s.Group+"/")
+			next, last := "-", "-"
+			if s.Next != nil {
+				next = s.Next.Format("2006-01-02 15:04:05")
+			}
+			if s.LastRun != nil {
+				last = fmt.Sprintf("%v(code:%v,duration:%v)", s.LastRun.Format("2006-01-02 15:04:05"), s.LastCode, s.LastDuration)
+			}
+			if !wide && scheduled {
+				fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", s.State, cell(name), cell(s.Group), pid, memory, cell(formatPorts(s.Ports)), next, last)
+				continue
+			}
+			if !wide {
+				fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\n", s.State, cell(name), cell(s.Group), pid, memory, cell(formatPorts(s.Ports)))
+				continue
+			}
+			args := []string{}
+			for _, arg := range s.Args {
+				args = append(args, quoteArg(arg))
+			}
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", s.State, cell(name), cell(s.Group), pid, memory,
+				cell(formatPorts(s.Ports)), cell(s.Path), cell(strings.Join(args, " ")), cell(s.Dir), next, last)
+		}
+	})
+}
+
+//writeItems will write slice items by format, the tmpl is executed on each item if it is not empty, table is called to write table/wide format
+func writeItems(out io.Writer, items interface{}, format, tmpl string, table func(writer *tabwriter.Writer, wide bool)) (err error) {
+	if len(tmpl) > 0 {
+		var t *template.Template
+		t, err = template.New("item").Funcs(template.FuncMap{"json": toJSON}).Parse(tmpl)
+		if err != nil {
+			err = fmt.Errorf("parse template fail with %v", err)
+			return
+		}
+		values := reflect.ValueOf(items)
+		for i := 0; i < values.Len(); i++ {
+			err = t.Execute(out, values.Index(i).Interface())
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(out, "\n")
+		}
+		return
+	}
+	switch format {
+	case FormatJSON:
+		var data []byte
+		data, err = json.MarshalIndent(items, "", "  ")
+		if err == nil {
+			fmt.Fprintf(out, "%s\n", data)
+		}
+	case FormatYAML:
+		var data []byte
+		data, err = yaml.Marshal(items)
+		if err == nil {
+			fmt.Fprintf(out, "%s", data)
+		}
+	case FormatTable, FormatWide, "":
+		writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		table(writer, format == FormatWide)
+		err = writer.Flush()
+	default:
+		err = fmt.Errorf("format %v is not supported, supported is %v/%v/%v/%v", format, FormatTable, FormatWide, FormatJSON, FormatYAML)
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package serviced
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["status"] = Group{
+		Name:     "status",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "a", Path: "/bin/sleep", Args: []string{"10"}},
+			{Name: "b", Path: "/bin/sh", Args: []string{"-c", "echo\ta b"}},
+		},
+	}
+	err := m.Start(ioutil.Discard, "status/a")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer m.StopAll()
+	status := m.Status("status")
+	if len(status) != 2 || (status[0].State != "running" && status[0].State != "ready") || status[0].Pid < 1 || status[1].State != "stopped" {
+		t.Errorf("%v", toJSON(status))
+		return
+	}
+	//json
+	buffer := bytes.NewBuffer(nil)
+	err = WriteStatus(buffer, status, FormatJSON, "")
+	decoded := []*Status{}
+	if err == nil {
+		err = json.Unmarshal(buffer.Bytes(), &decoded)
+	}
+	if err != nil || len(decoded) != 2 || decoded[1].Args[1] != "echo\ta b" {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	//yaml
+	buffer.Reset()
+	err = WriteStatus(buffer, status, FormatYAML, "")
+	decoded = []*Status{}
+	if err == nil {
+		err = yaml.Unmarshal(buffer.Bytes(), &decoded)
+	}
+	if err != nil || len(decoded) != 2 || decoded[0].Key != "status/a" {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	//table
+	buffer.Reset()
+	err = WriteStatus(buffer, status, FormatTable, "")
+	if err != nil || len(strings.Split(strings.TrimSpace(buffer.String()), "\n")) != 3 {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	if strings.Contains(buffer.String(), "NEXT") {
+		t.Errorf("%v", buffer.String())
+		return
+	}
+	next := time.Now()
+	scheduled := append([]*Status{{Key: "status/c", Group: "status", Name: "c", State: "scheduled", Next: &next}}, status...)
+	buffer.Reset()
+	err = WriteStatus(buffer, scheduled, FormatTable, "")
+	if err != nil || !strings.Contains(buffer.String(), "NEXT") || !strings.Contains(buffer.String(), next.Format("2006-01-02 15:04:05")) {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	buffer.Reset()
+	err = WriteStatus(buffer, status, FormatWide, "")
+	if err != nil || !strings.Contains(buffer.String(), `-c "echo\ta b"`) {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	//template
+	buffer.Reset()
+	err = WriteStatus(buffer, status, "", "{{.Key}} {{.Name}} {{json .Args}}")
+	if err != nil || buffer.String() != "status/a a [\"10\"]\nstatus/b b [\"-c\",\"echo\\ta b\"]\n" {
+		t.Errorf("%v,%v", err, buffer.String())
+		return
+	}
+	if WriteStatus(buffer, status, "xx", "") == nil || WriteStatus(buffer, status, "", "{{") == nil {
+		t.Error("error")
+		return
+	}
+}
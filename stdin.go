@@ -0,0 +1,101 @@
+package serviced
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+//StdinPipe is the stdin configure to keep a pipe to service stdin, the data is written by send command
+const StdinPipe = "pipe"
+
+//StdinFilePrefix is the stdin configure prefix to read service stdin from file like file:input.txt
+const StdinFilePrefix = "file:"
+
+//SendTimeout is the max time to wait service reading the sent data
+var SendTimeout = 5 * time.Second
+
+//openStdin will open the stdin of service, the pipe writer is returned when stdin is pipe, the pty slave/master is returned on tty,
+//the file is relative to working directory and resolved by outPath, others is used as literal string.
+//the returned closer should be closed by caller after service is started
+func openStdin(service *Service, outPath func(string) string, literal func(string) string) (reader io.Reader, closer io.Closer, writer *os.File, err error) {
+	switch {
+	case service.TTY:
+		var slave *os.File
+		writer, slave, err = openPty()
+		if err != nil {
+			return
+		}
+		reader, closer = slave, slave
+	case len(service.Stdin) < 1:
+	case service.Stdin == StdinPipe:
+		var pipe *os.File
+		pipe, writer, err = os.Pipe()
+		if err != nil {
+			err = fmt.Errorf("create stdin pipe fail with %v", err)
+			return
+		}
+		reader, closer = pipe, pipe
+	case strings.HasPrefix(service.Stdin, StdinFilePrefix):
+		var file *os.File
+		file, err = os.Open(outPath(strings.TrimPrefix(service.Stdin, StdinFilePrefix)))
+		if err != nil {
+			err = fmt.Errorf("open stdin fail with %v", err)
+			return
+		}
+		reader, closer = file, file
+	default:
+		reader = strings.NewReader(literal(service.Stdin))
+	}
+	return
+}
+
+//Send will write data to stdin of running service which is matched by group/service pattern,
+//the stdin of service must be pipe
+func (m *Manager) Send(pattern string, data []byte) (err error) {
+	sending := []*Running{}
+	m.locker.RLock()
+	for key, running := range m.running {
+		if matchKey(pattern, key) {
+			sending = append(sending, running)
+		}
+	}
+	m.locker.RUnlock()
+	if len(sending) < 1 {
+		err = fmt.Errorf("service %v is not running", pattern)
+		return
+	}
+	for _, running := range sending {
+		sendErr := running.send(data)
+		if sendErr != nil && err == nil {
+			err = fmt.Errorf("send to %v fail with %v", running.Key, sendErr)
+		}
+	}
+	return
+}
+
+//send will write data to stdin pipe, it fail when stdin is not pipe or service is not reading in SendTimeout
+func (r *Running) send(data []byte) (err error) {
+	r.stdinLocker.Lock()
+	defer r.stdinLocker.Unlock()
+	if r.stdin == nil {
+		err = fmt.Errorf("stdin is not pipe")
+		return
+	}
+	//deadline is not supported on some platform, the write is blocking on it
+	r.stdin.SetWriteDeadline(time.Now().Add(SendTimeout))
+	_, err = r.stdin.Write(data)
+	return
+}
+
+//closeStdin will close the stdin pipe
+func (r *Running) closeStdin() {
+	r.stdinLocker.Lock()
+	defer r.stdinLocker.Unlock()
+	if r.stdin != nil {
+		r.stdin.Close()
+		r.stdin = nil
+	}
+}
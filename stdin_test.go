@@ -0,0 +1,78 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer os.Remove("test-stdin.log")
+	defer os.Remove("test-stdin-literal.log")
+	defer os.Remove("test-stdin-file.log")
+	m := NewManager()
+	m.init()
+	m.Groups["stdin"] = Group{
+		Name:     "stdin",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "pipe", Path: "/bin/sh", Args: []string{"-c", "read a; echo got $a; read b; echo got $b"}, Stdin: StdinPipe, Stdout: "test-stdin.log"},
+			{Name: "literal", Path: "/bin/cat", Stdin: "hello ${INSTANCE}", Stdout: "test-stdin-literal.log", Type: ServiceOneshot},
+			{Name: "file", Path: "/bin/cat", Stdin: "file:test-service.json", Stdout: "test-stdin-file.log", Type: ServiceOneshot},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "stdin")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if data, _ := ioutil.ReadFile("test-stdin-literal.log"); string(data) != "hello 0" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	if data, _ := ioutil.ReadFile("test-stdin-file.log"); len(data) < 1 {
+		t.Error("error")
+		return
+	}
+	err = m.Send("stdin/pipe", []byte("a\n"))
+	if err == nil {
+		err = m.Send("stdin/p*", []byte("b\n"))
+	}
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("stdin/pipe", WaitExited, 3*time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(100 * time.Millisecond)
+	if data, _ := ioutil.ReadFile("test-stdin.log"); string(data) != "got a\ngot b\n" {
+		t.Errorf("%v", string(data))
+		return
+	}
+	//error
+	if m.Send("stdin/pipe", []byte("a\n")) == nil {
+		t.Error("error")
+		return
+	}
+	m.Groups["stdin"].Services[0].Stdin = ""
+	m.Start(ioutil.Discard, "stdin/pipe")
+	if m.Send("stdin/pipe", []byte("a\n")) == nil {
+		t.Error("error")
+		return
+	}
+	m.Groups["stdin"].Services[0].Stdin = "file:not-exists.txt"
+	m.Stop("stdin/pipe")
+	if m.Start(ioutil.Discard, "stdin/pipe") == nil {
+		t.Error("error")
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package serviced
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	//WaitRunning is waiting all target to be running
+	WaitRunning = "running"
+	//WaitReady is waiting all target to pass readiness probe
+	WaitReady = "ready"
+	//WaitStopped is waiting all target to be not running
+	WaitStopped = "stopped"
+	//WaitExited is waiting all target to be exited by itself, not stopped by manager
+	WaitExited = "exited"
+)
+
+//reached will return true if all key reached the state, it must be called with locker
+func (m *Manager) reached(keys []string, state string) bool {
+	for _, key := range keys {
+		running := m.running[key]
+		switch state {
+		case WaitRunning:
+			if running == nil {
+				return false
+			}
+		case WaitReady:
+			if running == nil || running.State != StateReady {
+				return false
+			}
+		case WaitStopped:
+			if running != nil {
+				return false
+			}
+		case WaitExited:
+			exited := m.exits[key]
+			if running != nil || exited == nil || exited.Requested {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//Wait will block until all service matched by pattern reach the state, state is running/ready/stopped/exited,
+//it will return error on timeout, timeout 0 is waiting forever.
+func (m *Manager) Wait(pattern, state string, timeout time.Duration) (err error) {
+	err = m.wait(pattern, state, timeout, nil)
+	return
+}
+
+//wait will block until all service matched by pattern reach the state, it will return error on timeout or closed is closed
+func (m *Manager) wait(pattern, state string, timeout time.Duration, closed <-chan int) (err error) {
+	switch state {
+	case WaitRunning, WaitReady, WaitStopped, WaitExited:
+	default:
+		err = fmt.Errorf("state %v is not supported, supported is %v/%v/%v/%v", state, WaitRunning, WaitReady, WaitStopped, WaitExited)
+		return
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern += "/*"
+	}
+	keys := []string{}
+	for _, service := range m.matchServices(pattern) {
+		keys = append(keys, service.Key)
+	}
+	if len(keys) < 1 {
+		err = fmt.Errorf("service %v is not exist", pattern)
+		return
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		m.locker.RLock()
+		reached := m.reached(keys, state)
+		changed := m.changed
+		m.locker.RUnlock()
+		if reached {
+			break
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			err = fmt.Errorf("wait %v to be %v timeout after %v", pattern, state, timeout)
+			return
+		case <-closed:
+			err = fmt.Errorf("wait %v to be %v is canceled by client closed", pattern, state)
+			return
+		}
+	}
+	return
+}
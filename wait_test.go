@@ -0,0 +1,112 @@
+package serviced
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["wait"] = Group{
+		Name:     "wait",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "short", Path: "/bin/sleep", Args: []string{"0.3"}, Ready: &Probe{Delay: "100ms"}},
+			{Name: "long", Path: "/bin/sleep", Args: []string{"10"}},
+		},
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.Start(ioutil.Discard, "wait")
+	}()
+	err := m.Wait("wait", WaitRunning, time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("wait/short", WaitReady, time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("wait/short", WaitExited, time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("wait/long", WaitStopped, 100*time.Millisecond)
+	if err == nil {
+		t.Error("error")
+		return
+	}
+	go m.Stop("wait/long")
+	err = m.Wait("wait/long", WaitStopped, time.Second)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = m.Wait("wait/long", WaitExited, 100*time.Millisecond)
+	if err == nil {
+		t.Error("error")
+		return
+	}
+	if m.Wait("wait", "xx", 0) == nil || m.Wait("none", WaitRunning, 0) == nil {
+		t.Error("error")
+		return
+	}
+}
+
+func TestWaitClosed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	m := NewManager()
+	m.init()
+	m.Groups["wait"] = Group{
+		Name:     "wait",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "long", Path: "/bin/sleep", Args: []string{"10"}},
+		},
+	}
+	//next command is processed on same connection after wait is done
+	client, server := net.Pipe()
+	done := make(chan int)
+	go func() {
+		m.procConn(server)
+		close(done)
+	}()
+	reader := bufio.NewReader(client)
+	fmt.Fprintf(client, "%v\n", toJSON([]string{"wait", "wait/long", WaitStopped, "0s"}))
+	reader.ReadString('\n')
+	if line, _ := reader.ReadString('\n'); line != "==OK:\n" {
+		t.Error(line)
+		return
+	}
+	fmt.Fprintf(client, "%v\n", toJSON([]string{"wait", "wait/long", "xx", "0s"}))
+	reader.ReadString('\n')
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "==ERR:") {
+		t.Error(line)
+		return
+	}
+	//the wait forever is canceled by client closed
+	fmt.Fprintf(client, "%v\n", toJSON([]string{"wait", "wait/long", WaitRunning, "0s"}))
+	reader.ReadString('\n')
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("wait is not canceled")
+		return
+	}
+}
@@ -0,0 +1,181 @@
+package serviced
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//WatchInterval is the interval to scan the watched files
+var WatchInterval = 500 * time.Millisecond
+
+//DefaultWatchDebounce is the default time to wait no more change before restarting
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+//serviceWatcher is the watcher to restart service on watched files changed
+type serviceWatcher struct {
+	key     string
+	dir     string
+	service *Service
+	stop    chan int
+}
+
+//watchDebounce will return the watch_debounce of service, default is 500ms
+func (s *Service) watchDebounce() (debounce time.Duration, err error) {
+	debounce = DefaultWatchDebounce
+	if len(s.WatchDebounce) > 0 {
+		debounce, err = time.ParseDuration(s.WatchDebounce)
+		if err != nil {
+			err = fmt.Errorf("parse watch_debounce %v fail with %v", s.WatchDebounce, err)
+		}
+	}
+	return
+}
+
+//checkWatch will check the watch and ignore pattern
+func (s *Service) checkWatch() (err error) {
+	for _, pattern := range append(append([]string{}, s.Watch...), s.WatchIgnore...) {
+		if _, err = filepath.Match(pattern, ""); err != nil {
+			err = fmt.Errorf("watch pattern %v is invalid", pattern)
+			return
+		}
+	}
+	_, err = s.watchDebounce()
+	return
+}
+
+//ignored will return true if the relative path or base name is matched by any ignore pattern
+func (w *serviceWatcher) ignored(path string) bool {
+	rel, err := filepath.Rel(w.dir, path)
+	if err != nil {
+		rel = path
+	}
+	for _, pattern := range w.service.WatchIgnore {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//scan will return the modify time and size of all watched file, the matched directory is scanned recursively
+func (w *serviceWatcher) scan() (files map[string]string) {
+	files = map[string]string{}
+	for _, pattern := range w.service.Watch {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(w.dir, pattern)
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if w.ignored(path) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !info.IsDir() {
+					files[path] = fmt.Sprintf("%v %v", info.ModTime().UnixNano(), info.Size())
+				}
+				return nil
+			})
+		}
+	}
+	return
+}
+
+//changedFile will return the first changed file between two scan
+func changedFile(last, current map[string]string) (file string, changed bool) {
+	for path, stat := range current {
+		if last[path] != stat {
+			return path, true
+		}
+	}
+	for path := range last {
+		if _, ok := current[path]; !ok {
+			return path, true
+		}
+	}
+	return
+}
+
+//startWatch will start watching the service files in dev mode, it is not started again when it is watching
+func (m *Manager) startWatch(group *Group, service *Service) {
+	if !m.Dev || len(service.Watch) < 1 {
+		return
+	}
+	key := group.Name + "/" + service.Name
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	if m.watchers[key] != nil {
+		return
+	}
+	watcher := &serviceWatcher{
+		key:     key,
+		dir:     filepath.Dir(group.Filename),
+		service: service,
+		stop:    make(chan int),
+	}
+	m.watchers[key] = watcher
+	log.Infof("%v is watching %v", key, service.Watch)
+	go m.runWatch(watcher)
+}
+
+//stopWatch will stop the watcher of service matched by match
+func (m *Manager) stopWatch(match func(key string) bool) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+	for key, watcher := range m.watchers {
+		if match(key) {
+			close(watcher.stop)
+			delete(m.watchers, key)
+		}
+	}
+}
+
+func (m *Manager) runWatch(watcher *serviceWatcher) {
+	debounce, _ := watcher.service.watchDebounce()
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	last := watcher.scan()
+	var changedAt time.Time
+	var changed string
+	for {
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+		}
+		current := watcher.scan()
+		if file, ok := changedFile(last, current); ok {
+			changed, changedAt, last = file, time.Now(), current
+		}
+		if changedAt.IsZero() || time.Since(changedAt) < debounce {
+			continue
+		}
+		changedAt = time.Time{}
+		log.Infof("%v is restarting by %v is changed", watcher.key, changed)
+		err := m.Restart(ioutil.Discard, watcher.key)
+		if err != nil {
+			log.Warnf("%v restart by watch fail with %v", watcher.key, err)
+		}
+	}
+}
+
+//watchMatch will return the match func of watcher key by pattern, pattern is all, group name or group/service with glob
+func watchMatch(pattern string) func(key string) bool {
+	return func(key string) bool {
+		return matchTarget(pattern, strings.SplitN(key, "/", 2)[0], key)
+	}
+}
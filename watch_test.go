@@ -0,0 +1,76 @@
+package serviced
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	os.MkdirAll("test-watch/sub", os.ModePerm)
+	defer os.RemoveAll("test-watch")
+	ioutil.WriteFile("test-watch/sub/a.conf", []byte("a"), os.ModePerm)
+	ioutil.WriteFile("test-watch/b.log", []byte("b"), os.ModePerm)
+	WatchInterval = 50 * time.Millisecond
+	defer func() { WatchInterval = 500 * time.Millisecond }()
+	m := NewManager()
+	m.init()
+	m.Dev = true
+	m.Groups["watch"] = Group{
+		Name:     "watch",
+		Filename: "test-service.json",
+		Services: []Service{
+			{Name: "web", Path: "/bin/sleep", Args: []string{"10"}, Watch: []string{"test-watch"}, WatchIgnore: []string{"*.log"}, WatchDebounce: "200ms"},
+		},
+	}
+	defer m.StopAll()
+	err := m.Start(ioutil.Discard, "watch")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pid := m.Status("watch/web")[0].Pid
+	//ignored
+	ioutil.WriteFile("test-watch/b.log", []byte("bb"), os.ModePerm)
+	time.Sleep(400 * time.Millisecond)
+	if m.Status("watch/web")[0].Pid != pid {
+		t.Error("error")
+		return
+	}
+	//debounce
+	for i := 0; i < 3; i++ {
+		ioutil.WriteFile("test-watch/sub/a.conf", []byte("aa"+string(rune('0'+i))), os.ModePerm)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if m.Status("watch/web")[0].Pid != pid {
+		t.Error("error")
+		return
+	}
+	time.Sleep(400 * time.Millisecond)
+	if len(m.History("watch/web")) != 1 || m.Status("watch/web")[0].Pid == pid || m.Status("watch/web")[0].Pid < 1 {
+		t.Errorf("%v,%v", toJSON(m.History("watch/web")), toJSON(m.Status("watch/web")))
+		return
+	}
+	//stop watching on stop
+	m.Stop("watch/web")
+	if len(m.watchers) != 0 {
+		t.Error("error")
+		return
+	}
+	ioutil.WriteFile("test-watch/sub/a.conf", []byte("aaa"), os.ModePerm)
+	time.Sleep(400 * time.Millisecond)
+	if m.Status("watch/web")[0].Pid > 0 {
+		t.Error("error")
+		return
+	}
+	//check
+	if (&Service{Name: "a", Path: "a", Watch: []string{"["}}).check() == nil || (&Service{Name: "a", Path: "a", WatchDebounce: "x"}).check() == nil {
+		t.Error("error")
+		return
+	}
+}